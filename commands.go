@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"typr/data"
+)
+
+// runCommand runs a subcommand such as "typr book import file.epub". It
+// returns false when args do not name a subcommand, in which case typr starts
+// the interactive menu.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	var err error
+	switch args[0] {
+	case "book":
+		err = bookCommand(args[1:])
+	case "wordlist":
+		err = wordListCommand(args[1:])
+	case "corpus":
+		err = corpusCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return true
+}
+
+func bookCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: typr book import [-force] <file> | typr book list")
+	}
+
+	switch args[0] {
+	case "import":
+		fs := flag.NewFlagSet("book import", flag.ExitOnError)
+		ascii := fs.Bool("ascii", true, "convert typographic quotes and dashes to ASCII")
+		force := fs.Bool("force", false, "replace a book that was imported before and reset its progress")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: typr book import [-ascii=false] [-force] <file>")
+		}
+
+		book, err := data.ImportBook(fs.Arg(0), *ascii, *force)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %q as %s: %d sections, %d words\n", book.Title, book.ID, len(book.Sections), book.TotalWords())
+		fmt.Printf("Start typing it with: typr -book %s\n", book.ID)
+
+	case "list":
+		books, err := data.LoadBooks()
+		if err != nil {
+			return err
+		}
+		if len(books) == 0 {
+			fmt.Println("No books imported yet.")
+		}
+		for _, book := range books {
+			fmt.Printf("%-24s %5.1f%%  %s\n", book.ID, book.Completion(), book.Title)
+		}
+
+	default:
+		return fmt.Errorf("unknown book command: %s", args[0])
+	}
+
+	return nil
+}
+
+func wordListCommand(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("usage: typr wordlist check [file or ID...]")
+	}
+
+	// Files are checked as they are, so that a list which cannot be
+	// registered can still be checked
+	var lists []data.WordList
+	var registry *data.Registry
+	for _, arg := range args[1:] {
+		if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+			lists = append(lists, data.WordList{ID: arg, Path: arg})
+			continue
+		}
+		if registry == nil {
+			var err error
+			if registry, err = data.DiscoverWordLists(); err != nil {
+				return err
+			}
+		}
+		list, ok := registry.Find(arg)
+		if !ok {
+			return fmt.Errorf("no word list file or ID named %s", arg)
+		}
+		lists = append(lists, list)
+	}
+
+	// Without arguments every known list is checked, including the ones that
+	// were skipped because they could not be read
+	if len(args) == 1 {
+		var err error
+		if registry, err = data.DiscoverWordLists(); err != nil {
+			return err
+		}
+		lists = registry.Lists
+		for _, skipped := range registry.Skipped {
+			lists = append(lists, data.WordList{ID: skipped.Path, Path: skipped.Path})
+		}
+	}
+
+	failed := 0
+	for _, list := range lists {
+		report, err := checkWordList(list)
+		if err != nil {
+			fmt.Printf("%s: %v\n", list.Path, err)
+			failed++
+			continue
+		}
+
+		for _, d := range report.Diagnostics {
+			fmt.Printf("%s: %s\n", list.Path, d)
+		}
+		fmt.Printf("%s: %s, %d words, %d errors, %d warnings\n", list.ID, report.Format, len(report.Words), report.Errors(), report.Warnings())
+		if report.Errors() > 0 {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d word lists are invalid", failed, len(lists))
+	}
+	return nil
+}
+
+func checkWordList(list data.WordList) (*data.WordListReport, error) {
+	file, err := list.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", list.Path, err)
+	}
+	defer file.Close()
+
+	return data.CheckWords(file)
+}
+
+func corpusCommand(args []string) error {
+	if len(args) == 0 || args[0] != "build" {
+		return fmt.Errorf("usage: typr corpus build [flags] <file or directory...>")
+	}
+
+	defaults := data.DefaultCorpusOptions()
+	fs := flag.NewFlagSet("corpus build", flag.ExitOnError)
+	output := fs.String("o", "-", "file to write the word list to, or - for stdout")
+	name := fs.String("name", "", "name of the word list")
+	language := fs.String("language", "", "language of the word list")
+	lower := fs.Bool("lower", defaults.Lowercase, "lowercase all words")
+	strip := fs.Bool("strip", defaults.StripPunctuation, "strip punctuation and drop tokens that are not words")
+	minLen := fs.Int("min-len", defaults.MinLength, "minimum word length")
+	minCount := fs.Int("min-count", defaults.MinCount, "minimum number of occurrences")
+	maxWords := fs.Int("max-words", 0, "keep only the most frequent words, 0 keeps all")
+	stopwords := fs.String("stopwords", "", "drop stopwords: english, or a file with one word per line")
+	fs.Parse(args[1:])
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: typr corpus build [flags] <file or directory...>")
+	}
+
+	options := data.CorpusOptions{
+		Lowercase:        *lower,
+		StripPunctuation: *strip,
+		MinLength:        *minLen,
+		MinCount:         *minCount,
+		MaxWords:         *maxWords,
+	}
+	var err error
+	options.Stopwords, err = data.LoadStopwords(*stopwords)
+	if err != nil {
+		return err
+	}
+
+	words, err := data.BuildWordList(fs.Args(), options)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		out, err = os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create word list: %w", err)
+		}
+		defer out.Close()
+	}
+	if err := data.WriteWordList(out, *name, *language, words); err != nil {
+		return fmt.Errorf("failed to write word list: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote %d words\n", len(words))
+	return nil
+}
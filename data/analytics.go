@@ -0,0 +1,215 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+	"typr/game"
+	"unicode"
+)
+
+// Keystrokes slower than this are pauses rather than typing speed.
+const maxKeystrokeLatency = 2 * time.Second
+
+// minSamples is the number of keystrokes needed before a key is judged.
+const minSamples = 5
+
+// adaptiveStrength controls how strongly weak keys raise a word's weight.
+const adaptiveStrength = 2.0
+
+// KeyStats accumulates how often a key or bigram was typed, how often it was
+// wrong, and the total latency of the timed keystrokes.
+type KeyStats struct {
+	Count   int
+	Errors  int
+	Timed   int
+	Latency time.Duration
+}
+
+func (k *KeyStats) ErrorRate() float64 {
+	if k.Count == 0 {
+		return 0
+	}
+	return float64(k.Errors) / float64(k.Count)
+}
+
+func (k *KeyStats) MeanLatency() time.Duration {
+	if k.Timed == 0 {
+		return 0
+	}
+	return k.Latency / time.Duration(k.Timed)
+}
+
+// WPM converts the mean latency to words per minute of five characters.
+func (k *KeyStats) WPM() float64 {
+	latency := k.MeanLatency()
+	if latency <= 0 {
+		return 0
+	}
+	return time.Minute.Seconds() / (latency.Seconds() * 5)
+}
+
+func (k *KeyStats) merge(other *KeyStats) {
+	k.Count += other.Count
+	k.Errors += other.Errors
+	k.Timed += other.Timed
+	k.Latency += other.Latency
+}
+
+func (k *KeyStats) add(correct bool, latency time.Duration) {
+	k.Count++
+	if !correct {
+		k.Errors++
+	}
+	if latency > 0 && latency < maxKeystrokeLatency {
+		k.Timed++
+		k.Latency += latency
+	}
+}
+
+// Analytics holds per-character and per-bigram statistics over all tests.
+// Bigrams are keyed by the two expected characters. Every keyboard layout
+// has its own analytics, since the same characters sit on different keys.
+type Analytics struct {
+	Layout  string `json:"-"`
+	Chars   map[string]*KeyStats
+	Bigrams map[string]*KeyStats
+}
+
+func NewAnalytics(layout string) *Analytics {
+	return &Analytics{
+		Layout:  layout,
+		Chars:   make(map[string]*KeyStats),
+		Bigrams: make(map[string]*KeyStats),
+	}
+}
+
+// analyticsPath keeps QWERTY analytics in analytics.json, where they were
+// before layouts could be emulated, and names the others after LayoutName.
+func analyticsPath(layout string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	name := LayoutName(layout)
+	if name == QWERTY {
+		return filepath.Join(dir, "analytics.json"), nil
+	}
+	return filepath.Join(dir, "analytics-"+name+".json"), nil
+}
+
+// LoadAnalytics reads the saved analytics of a layout. A missing file yields
+// empty analytics.
+func LoadAnalytics(layout string) (*Analytics, error) {
+	path, err := analyticsPath(layout)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewAnalytics(layout), nil
+		}
+		return nil, fmt.Errorf("failed to read analytics: %w", err)
+	}
+
+	analytics := NewAnalytics(layout)
+	if err := json.Unmarshal(content, analytics); err != nil {
+		return nil, fmt.Errorf("failed to parse analytics: %w", err)
+	}
+	return analytics, nil
+}
+
+func (a *Analytics) Save() error {
+	path, err := analyticsPath(a.Layout)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	content, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to save analytics: %w", err)
+	}
+	return nil
+}
+
+// Record adds the keystrokes of a test. Characters typed past the end of a
+// word have no expected key and are skipped, as are whitespace keys.
+func (a *Analytics) Record(keystrokes []game.Keystroke) {
+	for _, k := range keystrokes {
+		if k.Expected == 0 || unicode.IsSpace(k.Expected) {
+			continue
+		}
+		correct := k.Typed == k.Expected
+
+		char := string(k.Expected)
+		if a.Chars[char] == nil {
+			a.Chars[char] = &KeyStats{}
+		}
+		a.Chars[char].add(correct, k.Latency)
+
+		if k.Previous == 0 || unicode.IsSpace(k.Previous) {
+			continue
+		}
+		bigram := string(k.Previous) + char
+		if a.Bigrams[bigram] == nil {
+			a.Bigrams[bigram] = &KeyStats{}
+		}
+		a.Bigrams[bigram].add(correct, k.Latency)
+	}
+}
+
+// overall returns the combined statistics of all characters.
+func (a *Analytics) overall() KeyStats {
+	var total KeyStats
+	for _, stats := range a.Chars {
+		total.Count += stats.Count
+		total.Errors += stats.Errors
+		total.Timed += stats.Timed
+		total.Latency += stats.Latency
+	}
+	return total
+}
+
+// weakness scores how much worse than average stats are, combining the
+// excess error rate and how much slower than the mean they are typed. Keys
+// that are average or better, or that have too few samples, score zero.
+func weakness(stats *KeyStats, overall *KeyStats) float64 {
+	if stats == nil || stats.Count < minSamples {
+		return 0
+	}
+
+	score := math.Max(0, stats.ErrorRate()-overall.ErrorRate()) * 10
+	if mean := overall.MeanLatency(); mean > 0 && stats.Timed > 0 {
+		score += math.Max(0, float64(stats.MeanLatency())/float64(mean)-1)
+	}
+	return score
+}
+
+// wordWeight returns the factor by which a word's frequency is raised for
+// the weak characters and bigrams it contains, measured against the overall
+// stats. It is 1 for words without weaknesses.
+func (a *Analytics) wordWeight(word string, overall *KeyStats) float64 {
+	score := 0.0
+
+	var previous rune
+	for _, char := range word {
+		score += weakness(a.Chars[string(char)], overall)
+		if previous != 0 {
+			score += weakness(a.Bigrams[string(previous)+string(char)], overall)
+		}
+		previous = char
+	}
+
+	return 1 + adaptiveStrength*score
+}
@@ -0,0 +1,320 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// partWords is the section size used for plain text without chapters.
+const partWords = 1000
+
+type Section struct {
+	Title string
+	Words []string
+}
+
+// SectionResult aggregates the tests typed within one section.
+type SectionResult struct {
+	Section  int
+	WPM      float64
+	Accuracy float64
+	Words    int
+	Updated  time.Time
+}
+
+// BookProgress is the reading position within a book: the current section
+// and the number of words of it that have been typed.
+type BookProgress struct {
+	Section int
+	Offset  int
+	Results []SectionResult
+	Updated time.Time
+}
+
+// Book is an imported text that is typed through across sessions. Books are
+// stored as JSON in the books directory of the data directory, together with
+// their progress.
+type Book struct {
+	ID       string
+	Title    string
+	Sections []Section
+	Progress BookProgress
+}
+
+var (
+	chapterPattern = regexp.MustCompile(`(?i)^\s*(chapter|book|part)\s+([0-9]+|[ivxlcdm]+)\b.{0,60}$`)
+	idPattern      = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// ImportBook reads a plain text, Markdown or EPUB file into a book and saves
+// it to the data directory. A book that was imported before is only replaced,
+// and its progress reset, when force is set.
+func ImportBook(path string, ascii, force bool) (*Book, error) {
+	name := filepath.Base(path)
+	id := bookID(name)
+	if !force {
+		if existing, err := LoadBook(id); err == nil {
+			return nil, fmt.Errorf("book %s is already imported at %.1f%%, use -force to import it again and reset its progress", id, existing.Completion())
+		}
+	}
+
+	var sections []Section
+	var err error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".epub":
+		sections, err = readEPUBSections(path, ascii)
+	case ".md", ".markdown":
+		sections, err = readTextSections(path, ascii, true)
+	default:
+		sections, err = readTextSections(path, ascii, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("no text to type in %s", path)
+	}
+
+	book := &Book{
+		ID:       id,
+		Title:    strings.TrimSuffix(name, filepath.Ext(name)),
+		Sections: sections,
+	}
+	if err := book.Save(); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// bookID derives an ID from a file name. Names without ASCII letters or
+// digits, such as "日本.txt", get an ID from a hash of the name instead.
+func bookID(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	if id := strings.Trim(idPattern.ReplaceAllString(name, "-"), "-"); id != "" {
+		return id
+	}
+	return fmt.Sprintf("book-%x", sha256.Sum256([]byte(name)))[:13]
+}
+
+// markdownHeading returns the title of a Markdown heading line.
+func markdownHeading(line string) (string, bool) {
+	if !strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimLeft(line, "#")), true
+}
+
+// plainHeading recognizes chapter lines such as "Chapter 3" or "PART IV".
+func plainHeading(line string) (string, bool) {
+	if !chapterPattern.MatchString(line) {
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+var markdownSyntax = strings.NewReplacer("**", "", "__", "", "`", "", "* ", "", "> ", "")
+
+// readTextSections splits plain text at chapter lines, or Markdown at
+// headings with its inline syntax removed.
+func readTextSections(path string, ascii, markdown bool) ([]Section, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read book: %w", err)
+	}
+
+	heading := plainHeading
+	if markdown {
+		heading = markdownHeading
+	}
+
+	var sections []Section
+	current := Section{Title: "Beginning"}
+	for _, line := range strings.Split(string(content), "\n") {
+		if title, ok := heading(line); ok {
+			if len(current.Words) > 0 {
+				sections = append(sections, current)
+			}
+			current = Section{Title: title}
+			continue
+		}
+		if markdown {
+			line = markdownSyntax.Replace(line)
+		}
+		current.Words = append(current.Words, NormalizeText(line, ascii)...)
+	}
+	if len(current.Words) > 0 {
+		sections = append(sections, current)
+	}
+
+	// Without headings the text is split into parts of similar length
+	if len(sections) == 1 && len(sections[0].Words) > partWords {
+		words := sections[0].Words
+		sections = nil
+		for i, chunk := range SplitChunks(words, partWords) {
+			sections = append(sections, Section{Title: fmt.Sprintf("Part %d", i+1), Words: chunk})
+		}
+	}
+	return sections, nil
+}
+
+func BookDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "books"), nil
+}
+
+func (b *Book) Save() error {
+	dir, err := BookDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create book directory: %w", err)
+	}
+
+	content, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, b.ID+".json"), content, 0644); err != nil {
+		return fmt.Errorf("failed to save book: %w", err)
+	}
+	return nil
+}
+
+func LoadBook(id string) (*Book, error) {
+	dir, err := BookDir()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read book: %w", err)
+	}
+
+	var book Book
+	if err := json.Unmarshal(content, &book); err != nil {
+		return nil, fmt.Errorf("failed to parse book %s: %w", id, err)
+	}
+	return &book, nil
+}
+
+// LoadBooks returns all imported books, most recently typed first.
+func LoadBooks() ([]*Book, error) {
+	dir, err := BookDir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	books := make([]*Book, 0, len(paths))
+	for _, path := range paths {
+		book, err := LoadBook(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].Progress.Updated.After(books[j].Progress.Updated)
+	})
+	return books, nil
+}
+
+func (b *Book) TotalWords() int {
+	total := 0
+	for _, section := range b.Sections {
+		total += len(section.Words)
+	}
+	return total
+}
+
+func (b *Book) Finished() bool {
+	return b.Progress.Section >= len(b.Sections)
+}
+
+// Completion returns the percentage of the book's words typed so far.
+func (b *Book) Completion() float64 {
+	total := b.TotalWords()
+	if total == 0 {
+		return 100.0
+	}
+
+	typed := b.Progress.Offset
+	for i := 0; i < b.Progress.Section && i < len(b.Sections); i++ {
+		typed += len(b.Sections[i].Words)
+	}
+	return min(float64(typed)/float64(total)*100.0, 100.0)
+}
+
+// CurrentSection returns the section being typed, or nil once the book is
+// finished.
+func (b *Book) CurrentSection() *Section {
+	if b.Finished() {
+		return nil
+	}
+	return &b.Sections[b.Progress.Section]
+}
+
+// NextChunk returns about size words starting at the current position.
+func (b *Book) NextChunk(size int) []string {
+	section := b.CurrentSection()
+	if section == nil {
+		return nil
+	}
+	chunks := SplitChunks(section.Words[b.Progress.Offset:], size)
+	if len(chunks) == 0 {
+		return nil
+	}
+	return chunks[0]
+}
+
+// Advance moves the position forward by the words typed in a test and adds
+// the test to the results of the current section.
+func (b *Book) Advance(words int, wpm, accuracy float64) {
+	section := b.CurrentSection()
+	if section == nil || words <= 0 {
+		return
+	}
+
+	var result *SectionResult
+	for i := range b.Progress.Results {
+		if b.Progress.Results[i].Section == b.Progress.Section {
+			result = &b.Progress.Results[i]
+		}
+	}
+	if result == nil {
+		b.Progress.Results = append(b.Progress.Results, SectionResult{Section: b.Progress.Section})
+		result = &b.Progress.Results[len(b.Progress.Results)-1]
+	}
+
+	// Average the stats over all words typed in the section
+	total := result.Words + words
+	result.WPM = (result.WPM*float64(result.Words) + wpm*float64(words)) / float64(total)
+	result.Accuracy = (result.Accuracy*float64(result.Words) + accuracy*float64(words)) / float64(total)
+	result.Words = total
+	result.Updated = time.Now()
+
+	b.Progress.Offset += words
+	if b.Progress.Offset >= len(section.Words) {
+		b.Progress.Section++
+		b.Progress.Offset = 0
+	}
+	b.Progress.Updated = time.Now()
+}
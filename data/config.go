@@ -0,0 +1,46 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config holds the settings read from config.json in the user's config
+// directory, e.g. ~/.config/typr/config.json.
+type Config struct {
+	WordList string `json:"wordlist"`
+	Corpus   string `json:"corpus"`
+}
+
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "typr", "config.json"), nil
+}
+
+// LoadConfig reads the config file. A missing file yields the zero Config.
+func LoadConfig() (Config, error) {
+	var config Config
+
+	path, err := ConfigPath()
+	if err != nil {
+		return config, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,214 @@
+package data
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// corpusExts are the files read from directories given to BuildWordList.
+var corpusExts = []string{".txt", ".md", ".markdown", ".rst", ".adoc", ".org", ".tex"}
+
+// englishStopwords are very common English words that say little about a
+// text, for lists that should focus on its vocabulary.
+var englishStopwords = strings.Fields(`
+	a about after all also an and any are as at be because been but by can
+	could do does did for from had has have he her him his how i if in into
+	is it its just me more most my no not of on one only or other our out
+	she so some than that the their them then there these they this to up
+	us was we were what when which who will with would you your
+`)
+
+// CorpusOptions controls how the words of a corpus are normalized and which
+// of them end up in the list.
+type CorpusOptions struct {
+	Lowercase bool
+	// StripPunctuation trims punctuation around words and drops tokens that
+	// still contain anything but letters, apostrophes and hyphens
+	StripPunctuation bool
+	MinLength        int
+	MinCount         int
+	// MaxWords keeps only the most frequent words. Zero keeps all of them.
+	MaxWords  int
+	Stopwords map[string]bool
+}
+
+func DefaultCorpusOptions() CorpusOptions {
+	return CorpusOptions{
+		Lowercase:        true,
+		StripPunctuation: true,
+		MinLength:        1,
+		MinCount:         1,
+	}
+}
+
+// LoadStopwords returns the stopwords named by spec: "english" for the
+// built-in list, or the path of a file with one word per line. An empty spec
+// has no stopwords.
+func LoadStopwords(spec string) (map[string]bool, error) {
+	stopwords := make(map[string]bool)
+	if spec == "" {
+		return stopwords, nil
+	}
+
+	words := englishStopwords
+	if spec != "english" {
+		content, err := os.ReadFile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stopwords: %w", err)
+		}
+		words = strings.Fields(string(content))
+	}
+
+	for _, word := range words {
+		stopwords[strings.ToLower(word)] = true
+	}
+	return stopwords, nil
+}
+
+// normalizeToken applies the options to a single token, returning "" for
+// tokens that are dropped.
+func (o CorpusOptions) normalizeToken(token string) string {
+	token = asciiReplacer.Replace(token)
+	if o.Lowercase {
+		token = strings.ToLower(token)
+	}
+
+	if o.StripPunctuation {
+		token = strings.TrimFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+		valid := !strings.ContainsFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '\'' && r != '-'
+		})
+		if !valid {
+			return ""
+		}
+	}
+
+	// A leading # would read back as a comment
+	if strings.HasPrefix(token, "#") {
+		return ""
+	}
+	if len([]rune(token)) < o.MinLength || o.Stopwords[strings.ToLower(token)] {
+		return ""
+	}
+	return token
+}
+
+// CountWords adds the words of r to counts.
+func (o CorpusOptions) CountWords(r io.Reader, counts map[string]int) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		if word := o.normalizeToken(scanner.Text()); word != "" {
+			counts[word]++
+		}
+	}
+	return scanner.Err()
+}
+
+// BuildWordList counts the words of the given files, and of the text files
+// found in the given directories, and returns them from most to least
+// frequent.
+func BuildWordList(paths []string, options CorpusOptions) ([]Word, error) {
+	counts := make(map[string]int)
+	countFile := func(path string) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open corpus file: %w", err)
+		}
+		defer file.Close()
+
+		if err := options.CountWords(file, counts); err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return nil
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if err := countFile(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if file != path && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !slices.Contains(corpusExts, strings.ToLower(filepath.Ext(file))) {
+				return nil
+			}
+			return countFile(file)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	words := make([]Word, 0, len(counts))
+	for text, count := range counts {
+		if count >= options.MinCount {
+			words = append(words, Word{Text: text, Frequency: count})
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Frequency != words[j].Frequency {
+			return words[i].Frequency > words[j].Frequency
+		}
+		return words[i].Text < words[j].Text
+	})
+
+	if options.MaxWords > 0 && len(words) > options.MaxWords {
+		words = words[:options.MaxWords]
+	}
+	if len(words) == 0 {
+		return nil, ErrEmptyWordBank
+	}
+	return words, nil
+}
+
+// WriteWordList writes words in the word,frequency format with name and
+// language headers, as read by LoadWords and the word list registry.
+func WriteWordList(w io.Writer, name, language string, words []Word) error {
+	if name != "" {
+		if _, err := fmt.Fprintf(w, "# name: %s\n", name); err != nil {
+			return err
+		}
+	}
+	if language != "" {
+		if _, err := fmt.Fprintf(w, "# language: %s\n", language); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	for _, word := range words {
+		if err := writer.Write([]string{word.Text, strconv.Itoa(word.Frequency)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,101 @@
+package data
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const DefaultChunkWords = 50
+
+// TextOptions controls how custom text is prepared for typing.
+type TextOptions struct {
+	// ASCII replaces typographic quotes, dashes and ellipses with the
+	// characters found on a keyboard
+	ASCII bool
+	// ChunkWords is the approximate number of words per test
+	ChunkWords int
+}
+
+var asciiReplacer = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"", "″", "\"",
+	"«", "\"", "»", "\"",
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-",
+	"…", "...",
+	"\u00a0", " ", "\u202f", " ",
+)
+
+// ReadTextFile reads custom text from path, or from stdin when path is "-".
+func ReadTextFile(path string) (string, error) {
+	if path == "-" {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return string(content), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read text file: %w", err)
+	}
+	return string(content), nil
+}
+
+// NormalizeText collapses all whitespace into single spaces, drops control
+// characters and optionally converts typographic punctuation to ASCII.
+func NormalizeText(text string, ascii bool) []string {
+	if ascii {
+		text = asciiReplacer.Replace(text)
+	}
+
+	text = strings.Map(func(char rune) rune {
+		if unicode.IsSpace(char) || unicode.IsPrint(char) {
+			return char
+		}
+		return -1
+	}, text)
+
+	return strings.Fields(text)
+}
+
+// SplitChunks splits words into chunks of roughly size words. A chunk ends
+// at the first sentence end after reaching size, or at 1.5 times size if no
+// sentence ends before that.
+func SplitChunks(words []string, size int) [][]string {
+	if size <= 0 {
+		size = DefaultChunkWords
+	}
+
+	var chunks [][]string
+	start := 0
+	for i, word := range words {
+		length := i - start + 1
+		sentenceEnd := strings.ContainsAny(word[len(word)-1:], ".?!")
+		if (length >= size && sentenceEnd) || length >= size+size/2 {
+			chunks = append(chunks, words[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(words) {
+		chunks = append(chunks, words[start:])
+	}
+	return chunks
+}
+
+// LoadCustomText reads, normalizes and chunks the text at path.
+func LoadCustomText(path string, options TextOptions) ([][]string, error) {
+	text, err := ReadTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	words := NormalizeText(text, options.ASCII)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no text to type in %s", path)
+	}
+	return SplitChunks(words, options.ChunkWords), nil
+}
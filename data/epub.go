@@ -0,0 +1,165 @@
+package data
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Manifest []struct {
+		ID   string `xml:"id,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// readEPUBSections reads the documents of an EPUB in reading order. Each
+// document becomes a section titled by its first heading.
+func readEPUBSections(filename string, ascii bool) ([]Section, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open epub: %w", err)
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var container epubContainer
+	if err := decodeZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("epub has no package document")
+	}
+
+	packagePath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := decodeZipXML(files, packagePath, &pkg); err != nil {
+		return nil, err
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = path.Join(path.Dir(packagePath), item.Href)
+	}
+
+	var sections []Section
+	for _, ref := range pkg.Spine {
+		file, ok := files[hrefs[ref.IDRef]]
+		if !ok {
+			continue
+		}
+
+		title, text, err := readXHTMLText(file)
+		if err != nil {
+			return nil, err
+		}
+
+		words := NormalizeText(text, ascii)
+		if len(words) == 0 {
+			continue
+		}
+		if title == "" {
+			title = fmt.Sprintf("Section %d", len(sections)+1)
+		}
+		sections = append(sections, Section{Title: title, Words: words})
+	}
+
+	return sections, nil
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v any) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("epub is missing %s", name)
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(reader).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// readXHTMLText extracts the body text of an XHTML document along with its
+// first heading.
+func readXHTMLText(file *zip.File) (string, string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(reader)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var text, heading strings.Builder
+	inBody, inHeading, skip := false, false, 0
+	title := ""
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse %s: %w", file.Name, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "body":
+				inBody = true
+			case "script", "style":
+				skip++
+			case "h1", "h2", "h3":
+				inHeading = title == ""
+			}
+		case xml.EndElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "script", "style":
+				skip--
+			case "h1", "h2", "h3":
+				if inHeading {
+					title = strings.Join(strings.Fields(heading.String()), " ")
+					inHeading = false
+				}
+			}
+			// Block elements separate words
+			text.WriteString(" ")
+		case xml.CharData:
+			if !inBody || skip > 0 {
+				continue
+			}
+			if inHeading {
+				heading.Write(t)
+			}
+			text.Write(t)
+		}
+	}
+
+	return title, text.String(), nil
+}
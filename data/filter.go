@@ -0,0 +1,227 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrNoMatchingWords = errors.New("no words match the filter")
+
+// CharSets are named character sets for common drills.
+var CharSets = map[string]string{
+	"home-row":   "asdfghjkl",
+	"left-hand":  "qwertasdfgzxcvb",
+	"right-hand": "yuiophjklnm",
+}
+
+// RankBands are the frequency bands offered in the settings.
+var RankBands = []string{"200", "1k", "1k-5k"}
+
+// ResolveCharSet returns the characters of a named set, or set itself when
+// it is not a known name.
+func ResolveCharSet(set string) string {
+	if chars, ok := CharSets[set]; ok {
+		return chars
+	}
+	return set
+}
+
+// WordFilter narrows a word bank down for focused drills. Zero values do not
+// filter. Characters are compared case-insensitively, and ranks count from 1
+// for the most frequent word of the unfiltered list.
+type WordFilter struct {
+	MinLength int
+	MaxLength int
+
+	// Allowed limits words to these characters, Required must all appear in
+	// a word, and Excluded must not appear at all
+	Allowed  string
+	Required string
+	Excluded string
+
+	MinRank int
+	MaxRank int
+}
+
+func (f WordFilter) IsZero() bool {
+	return f == WordFilter{}
+}
+
+func (f WordFilter) String() string {
+	var parts []string
+	switch {
+	case f.MinLength > 0 && f.MaxLength > 0:
+		parts = append(parts, fmt.Sprintf("length %d-%d", f.MinLength, f.MaxLength))
+	case f.MinLength > 0:
+		parts = append(parts, fmt.Sprintf("length %d+", f.MinLength))
+	case f.MaxLength > 0:
+		parts = append(parts, fmt.Sprintf("length up to %d", f.MaxLength))
+	}
+	if f.Allowed != "" {
+		parts = append(parts, "only "+f.Allowed)
+	}
+	if f.Required != "" {
+		parts = append(parts, "with "+f.Required)
+	}
+	if f.Excluded != "" {
+		parts = append(parts, "without "+f.Excluded)
+	}
+	switch {
+	case f.MinRank > 1 && f.MaxRank > 0:
+		parts = append(parts, fmt.Sprintf("ranks %d-%d", f.MinRank, f.MaxRank))
+	case f.MinRank > 1:
+		parts = append(parts, fmt.Sprintf("ranks %d+", f.MinRank))
+	case f.MaxRank > 0:
+		parts = append(parts, fmt.Sprintf("top %d", f.MaxRank))
+	}
+
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Flags returns the command line flags that select the same filter, so that
+// a test can be recreated from its result.
+func (f WordFilter) Flags() string {
+	var flags []string
+	add := func(name, value string) {
+		flags = append(flags, "-"+name+"="+value)
+	}
+
+	if f.MinLength > 0 {
+		add("min-len", strconv.Itoa(f.MinLength))
+	}
+	if f.MaxLength > 0 {
+		add("max-len", strconv.Itoa(f.MaxLength))
+	}
+	if f.Allowed != "" {
+		add("allowed", f.Allowed)
+	}
+	if f.Required != "" {
+		add("require", f.Required)
+	}
+	if f.Excluded != "" {
+		add("exclude", f.Excluded)
+	}
+	if f.MaxRank > 0 {
+		band := strconv.Itoa(f.MaxRank)
+		if f.MinRank > 1 {
+			band = strconv.Itoa(f.MinRank-1) + "-" + band
+		}
+		add("band", band)
+	}
+	return strings.Join(flags, " ")
+}
+
+// ParseRankBand parses a frequency band such as "200" for the top 200
+// words or "1k-5k" for the words ranked after the first thousand up to the
+// five thousandth.
+func ParseRankBand(band string) (minRank, maxRank int, err error) {
+	if band == "" {
+		return 0, 0, nil
+	}
+
+	from, to, found := strings.Cut(band, "-")
+	if !found {
+		to, from = from, "0"
+	}
+
+	lower, err := parseRank(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	upper, err := parseRank(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if upper <= lower {
+		return 0, 0, fmt.Errorf("invalid frequency band %q: upper rank must be above the lower one", band)
+	}
+	return lower + 1, upper, nil
+}
+
+func parseRank(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	scale := 1
+	if rest, ok := strings.CutSuffix(s, "k"); ok {
+		s, scale = rest, 1000
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rank %q", s)
+	}
+	return n * scale, nil
+}
+
+func (f WordFilter) matches(word string) bool {
+	length := utf8.RuneCountInString(word)
+	if f.MinLength > 0 && length < f.MinLength {
+		return false
+	}
+	if f.MaxLength > 0 && length > f.MaxLength {
+		return false
+	}
+
+	lower := strings.ToLower(word)
+	if f.Allowed != "" {
+		for _, char := range lower {
+			if !strings.ContainsRune(strings.ToLower(f.Allowed), char) {
+				return false
+			}
+		}
+	}
+	for _, char := range strings.ToLower(f.Required) {
+		if !strings.ContainsRune(lower, char) {
+			return false
+		}
+	}
+	return !strings.ContainsAny(lower, strings.ToLower(f.Excluded))
+}
+
+// Filter returns a bank with only the words that match f, keeping their
+// frequencies. It fails with ErrNoMatchingWords when nothing is left.
+func (wb *WordBank) Filter(f WordFilter) (*WordBank, error) {
+	ranks := wb.ranks()
+
+	var words []Word
+	for i, word := range wb.Words {
+		if f.MinRank > 0 && ranks[i] < f.MinRank {
+			continue
+		}
+		if f.MaxRank > 0 && ranks[i] > f.MaxRank {
+			continue
+		}
+		if f.matches(word.Text) {
+			words = append(words, word)
+		}
+	}
+
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%w (%s) among %d words", ErrNoMatchingWords, f, len(wb.Words))
+	}
+	return NewWordBank(words)
+}
+
+// ranks returns the frequency rank of each word, with ties ranked in list
+// order.
+func (wb *WordBank) ranks() []int {
+	order := make([]int, len(wb.Words))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return wb.Words[order[i]].Frequency > wb.Words[order[j]].Frequency
+	})
+
+	ranks := make([]int, len(wb.Words))
+	for rank, i := range order {
+		ranks[i] = rank + 1
+	}
+	return ranks
+}
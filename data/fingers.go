@@ -0,0 +1,70 @@
+package data
+
+import (
+	"unicode/utf8"
+)
+
+// RowNames name the rows of a layout from the number row down.
+var RowNames = []string{"number", "top", "home", "bottom"}
+
+// FingerStats aggregates the per-character and per-bigram analytics by the
+// fingers, hands and rows that type them on a layout.
+type FingerStats struct {
+	Fingers map[Finger]*KeyStats
+	Hands   map[Hand]*KeyStats
+	Rows    map[int]*KeyStats
+
+	// SameFinger holds the bigrams typed with one finger on two different
+	// keys, and Bigrams all bigrams for comparison
+	SameFinger KeyStats
+	Bigrams    KeyStats
+}
+
+// ByFinger aggregates the analytics using the finger model of layout.
+// Characters that the layout does not have are left out.
+func (a *Analytics) ByFinger(layout *Layout) *FingerStats {
+	stats := &FingerStats{
+		Fingers: make(map[Finger]*KeyStats),
+		Hands:   make(map[Hand]*KeyStats),
+		Rows:    make(map[int]*KeyStats),
+	}
+
+	for char, keyStats := range a.Chars {
+		r, _ := utf8.DecodeRuneInString(char)
+		info, ok := layout.Locate(r)
+		if !ok {
+			continue
+		}
+
+		mergeInto(stats.Fingers, info.Finger, keyStats)
+		mergeInto(stats.Hands, info.Finger.Hand(), keyStats)
+		mergeInto(stats.Rows, info.Row, keyStats)
+	}
+
+	for bigram, keyStats := range a.Bigrams {
+		first, size := utf8.DecodeRuneInString(bigram)
+		second, _ := utf8.DecodeRuneInString(bigram[size:])
+		from, ok := layout.Locate(first)
+		if !ok {
+			continue
+		}
+		to, ok := layout.Locate(second)
+		if !ok {
+			continue
+		}
+
+		stats.Bigrams.merge(keyStats)
+		if from.Finger == to.Finger && from.KeyPosition != to.KeyPosition {
+			stats.SameFinger.merge(keyStats)
+		}
+	}
+
+	return stats
+}
+
+func mergeInto[K comparable](m map[K]*KeyStats, key K, keyStats *KeyStats) {
+	if m[key] == nil {
+		m[key] = &KeyStats{}
+	}
+	m[key].merge(keyStats)
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// WordListFormat is the file format of a word list.
+type WordListFormat int
+
+const (
+	// FormatCSV has a word,frequency pair on every line
+	FormatCSV WordListFormat = iota
+	// FormatPlain has one word per line, ordered from most to least common
+	FormatPlain
+	// FormatJSON is monkeytype's language format
+	FormatJSON
+)
+
+func (f WordListFormat) String() string {
+	switch f {
+	case FormatPlain:
+		return "plain"
+	case FormatJSON:
+		return "monkeytype JSON"
+	default:
+		return "CSV"
+	}
+}
+
+// detectSize and detectLines limit how much of a word list DetectFormat
+// looks at.
+const (
+	detectSize  = 64 * 1024
+	detectLines = 20
+)
+
+// zipfScale is the frequency given to the most common word of a list
+// without frequencies. The word at rank n gets 1/n of it.
+const zipfScale = 100000
+
+// monkeytypeList is a language file of monkeytype, such as english_1k.json.
+type monkeytypeList struct {
+	Name               string   `json:"name"`
+	OrderedByFrequency bool     `json:"orderedByFrequency"`
+	Words              []string `json:"words"`
+}
+
+// ZipfFrequency estimates the frequency of the word at rank, counting from
+// 1, following Zipf's law.
+func ZipfFrequency(rank int) int {
+	return max(1, zipfScale/rank)
+}
+
+// DetectFormat guesses the format of a word list from its first lines that
+// are neither empty nor comments, without consuming any input. A list
+// starting with { is JSON, a list with lines ending in a comma and a number
+// is CSV, and anything else is a plain list. A single broken line does not
+// turn a CSV list into a plain one.
+func DetectFormat(r *bufio.Reader) WordListFormat {
+	// A short read just means a small file
+	content, _ := r.Peek(detectSize)
+
+	lines := 0
+	for line := range bytes.Lines(content) {
+		text := strings.TrimSpace(string(line))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if lines == 0 && strings.HasPrefix(text, "{") {
+			return FormatJSON
+		}
+		if i := strings.LastIndex(text, ","); i >= 0 {
+			if _, err := strconv.Atoi(strings.TrimSpace(text[i+1:])); err == nil {
+				return FormatCSV
+			}
+		}
+
+		lines++
+		if lines == detectLines {
+			break
+		}
+	}
+
+	if lines == 0 {
+		return FormatCSV
+	}
+	return FormatPlain
+}
@@ -0,0 +1,336 @@
+package data
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// QWERTY is the layout the operating system is assumed to type with.
+const QWERTY = "qwerty"
+
+//go:embed layouts/*.txt
+var builtinLayouts embed.FS
+
+// Key is a key of a layout with the characters it types with and without
+// shift.
+type Key struct {
+	Base  rune
+	Shift rune
+}
+
+// KeyPosition is where a key sits on the keyboard. Row 0 is the number row
+// and columns count from the left end of each row.
+type KeyPosition struct {
+	Row    int
+	Column int
+}
+
+// Finger is the finger that presses a key, numbered from the left pinky to
+// the right pinky.
+type Finger int
+
+const (
+	LeftPinky Finger = iota
+	LeftRing
+	LeftMiddle
+	LeftIndex
+	LeftThumb
+	RightThumb
+	RightIndex
+	RightMiddle
+	RightRing
+	RightPinky
+)
+
+var fingerNames = []string{
+	"left pinky", "left ring", "left middle", "left index", "left thumb",
+	"right thumb", "right index", "right middle", "right ring", "right pinky",
+}
+
+func (f Finger) String() string {
+	if f < 0 || int(f) >= len(fingerNames) {
+		return "unknown"
+	}
+	return fingerNames[f]
+}
+
+// Hand is the hand of a finger.
+type Hand int
+
+const (
+	Left Hand = iota
+	Right
+)
+
+func (h Hand) String() string {
+	if h == Left {
+		return "left"
+	}
+	return "right"
+}
+
+func (f Finger) Hand() Hand {
+	if f <= LeftThumb {
+		return Left
+	}
+	return Right
+}
+
+// standardFingers assigns the keys of each row to fingers the way touch
+// typing is usually taught on a row-staggered keyboard. Keys past the end of
+// a row belong to the right pinky.
+var standardFingers = [][]Finger{
+	{LeftPinky, LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex, RightIndex, RightIndex, RightMiddle, RightRing, RightPinky},
+	{LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex, RightIndex, RightIndex, RightMiddle, RightRing, RightPinky},
+	{LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex, RightIndex, RightIndex, RightMiddle, RightRing, RightPinky},
+	{LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex, RightIndex, RightIndex, RightMiddle, RightRing, RightPinky},
+}
+
+// Layout is a keyboard layout, described as rows of keys on an ANSI
+// keyboard. Fingers holds the finger of every key, row by row.
+type Layout struct {
+	ID      string
+	Name    string
+	Rows    [][]Key
+	Fingers [][]Finger
+}
+
+// KeyInfo describes the key that types a character.
+type KeyInfo struct {
+	KeyPosition
+	Finger Finger
+	Shift  bool
+}
+
+// UserLayoutDir returns the directory for user-provided layouts.
+func UserLayoutDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "layouts"), nil
+}
+
+// LayoutName returns the name that data recorded with a layout is saved
+// under: its ID, or the file name without extension for a layout loaded from
+// a file. No layout is QWERTY.
+func LayoutName(id string) string {
+	if id == "" {
+		return QWERTY
+	}
+	return strings.TrimSuffix(filepath.Base(id), filepath.Ext(id))
+}
+
+// LayoutIDs lists the built-in layouts and those in the user's layout
+// directory. Layouts that fail to load are left out.
+func LayoutIDs() []string {
+	var ids []string
+	paths, _ := fs.Glob(builtinLayouts, "layouts/*.txt")
+	if dir, err := UserLayoutDir(); err == nil {
+		userPaths, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+		paths = append(paths, userPaths...)
+	}
+
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".txt")
+		if slices.Contains(ids, id) {
+			continue
+		}
+		if _, err := LoadLayout(id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// LoadLayout loads a layout by ID, looking in the user's layout directory
+// before the built-in layouts, or from a file when id is a path.
+func LoadLayout(id string) (*Layout, error) {
+	if strings.ContainsRune(id, os.PathSeparator) || filepath.Ext(id) == ".txt" {
+		return loadLayoutFile(id)
+	}
+
+	if dir, err := UserLayoutDir(); err == nil {
+		layout, err := loadLayoutFile(filepath.Join(dir, id+".txt"))
+		if err == nil || !os.IsNotExist(err) {
+			return layout, err
+		}
+	}
+
+	file, err := builtinLayouts.Open("layouts/" + id + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("unknown layout: %s", id)
+	}
+	defer file.Close()
+	return ParseLayout(file, id)
+}
+
+func loadLayoutFile(path string) (*Layout, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseLayout(file, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+}
+
+// ParseLayout reads a layout file. Each line lists the keys of a row,
+// separated by spaces, from the number row down. A key is its character
+// followed by its shifted character, which can be left out for letters.
+// Comment lines may set the name with "# name:".
+//
+// Keys are assigned to fingers as in standard touch typing, unless the file
+// has a "fingers:" line followed by rows of finger numbers, from 0 for the
+// left pinky to 9 for the right pinky, for layouts typed differently.
+func ParseLayout(r io.Reader, id string) (*Layout, error) {
+	layout := &Layout{ID: id, Name: id}
+	fingers := false
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if comment, ok := strings.CutPrefix(text, "#"); ok {
+			if key, value, found := strings.Cut(comment, ":"); found && strings.TrimSpace(key) == "name" {
+				layout.Name = strings.TrimSpace(value)
+			}
+			continue
+		}
+		if text == "fingers:" {
+			fingers = true
+			continue
+		}
+
+		if fingers {
+			row, err := parseFingers(text)
+			if err != nil {
+				return nil, fmt.Errorf("layout %s, line %d: %w", id, line, err)
+			}
+			layout.Fingers = append(layout.Fingers, row)
+			continue
+		}
+
+		var row []Key
+		for _, field := range strings.Fields(text) {
+			runes := []rune(field)
+			switch len(runes) {
+			case 1:
+				row = append(row, Key{Base: runes[0], Shift: unicode.ToUpper(runes[0])})
+			case 2:
+				row = append(row, Key{Base: runes[0], Shift: runes[1]})
+			default:
+				return nil, fmt.Errorf("layout %s, line %d: key %q must be one or two characters", id, line, field)
+			}
+		}
+		layout.Rows = append(layout.Rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read layout: %w", err)
+	}
+
+	if len(layout.Rows) == 0 {
+		return nil, fmt.Errorf("layout %s has no keys", id)
+	}
+	if fingers {
+		if len(layout.Fingers) != len(layout.Rows) {
+			return nil, fmt.Errorf("layout %s has %d rows of keys but %d rows of fingers", id, len(layout.Rows), len(layout.Fingers))
+		}
+		for i, row := range layout.Rows {
+			if len(layout.Fingers[i]) != len(row) {
+				return nil, fmt.Errorf("layout %s has %d keys but %d fingers in row %d", id, len(row), len(layout.Fingers[i]), i+1)
+			}
+		}
+	} else {
+		layout.Fingers = standardFingers
+	}
+	return layout, nil
+}
+
+func parseFingers(text string) ([]Finger, error) {
+	var row []Finger
+	for _, field := range strings.Fields(text) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < int(LeftPinky) || n > int(RightPinky) {
+			return nil, fmt.Errorf("finger %q must be a number from 0 to 9", field)
+		}
+		row = append(row, Finger(n))
+	}
+	return row, nil
+}
+
+// Locate returns the key that types char.
+func (l *Layout) Locate(char rune) (KeyInfo, bool) {
+	for row, keys := range l.Rows {
+		for column, key := range keys {
+			if char != key.Base && char != key.Shift {
+				continue
+			}
+			pos := KeyPosition{row, column}
+			return KeyInfo{KeyPosition: pos, Finger: l.FingerAt(pos), Shift: char != key.Base}, true
+		}
+	}
+	return KeyInfo{}, false
+}
+
+// FingerAt returns the finger of the key at pos.
+func (l *Layout) FingerAt(pos KeyPosition) Finger {
+	if pos.Row >= len(l.Fingers) {
+		return RightPinky
+	}
+	fingers := l.Fingers[pos.Row]
+	if pos.Column >= len(fingers) {
+		return RightPinky
+	}
+	return fingers[pos.Column]
+}
+
+// KeyAt returns the key at pos, if the layout has one there.
+func (l *Layout) KeyAt(pos KeyPosition) (Key, bool) {
+	if pos.Row >= len(l.Rows) || pos.Column >= len(l.Rows[pos.Row]) {
+		return Key{}, false
+	}
+	return l.Rows[pos.Row][pos.Column], true
+}
+
+// Remapper translates characters typed on one layout into those the same
+// keys type on another, to emulate a layout the system is not set to.
+type Remapper struct {
+	keys map[rune]rune
+}
+
+// NewRemapper maps the keys of from, the system layout, to those of to.
+func NewRemapper(from, to *Layout) *Remapper {
+	remapper := &Remapper{keys: make(map[rune]rune)}
+	for row, keys := range from.Rows {
+		for column, key := range keys {
+			target, ok := to.KeyAt(KeyPosition{row, column})
+			if !ok {
+				continue
+			}
+			remapper.keys[key.Base] = target.Base
+			remapper.keys[key.Shift] = target.Shift
+		}
+	}
+	return remapper
+}
+
+// Remap returns the character char types on the emulated layout. Characters
+// outside the layout, like space, are left alone.
+func (r *Remapper) Remap(char rune) rune {
+	if mapped, ok := r.keys[char]; ok {
+		return mapped
+	}
+	return char
+}
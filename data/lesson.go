@@ -0,0 +1,381 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"typr/game"
+	"unicode"
+)
+
+const (
+	// LessonTargetWPM and LessonTargetAccuracy must be reached on every
+	// unlocked letter before the next one is unlocked
+	LessonTargetWPM      = 35
+	LessonTargetAccuracy = 0.95
+
+	lessonStartLetters = 6
+	lessonMinSamples   = 20
+
+	// lessonSmoothing weighs each new keystroke in the moving averages
+	lessonSmoothing = 0.1
+
+	lessonWords     = 30
+	minPseudoLength = 3
+	maxPseudoLength = 8
+	focusAttempts   = 5
+)
+
+// LetterStats tracks exponential moving averages of the speed and accuracy
+// of a single letter.
+type LetterStats struct {
+	Samples  int
+	Accuracy float64
+	Latency  time.Duration
+}
+
+// WPM converts the average latency to words per minute of five characters.
+func (s *LetterStats) WPM() float64 {
+	if s.Latency <= 0 {
+		return 0
+	}
+	return time.Minute.Seconds() / (s.Latency.Seconds() * 5)
+}
+
+func (s *LetterStats) Ready() bool {
+	return s.Samples >= lessonMinSamples && s.Accuracy >= LessonTargetAccuracy && s.WPM() >= LessonTargetWPM
+}
+
+func (s *LetterStats) add(correct bool, latency time.Duration) {
+	hit := 0.0
+	if correct {
+		hit = 1
+	}
+
+	if s.Samples == 0 {
+		s.Accuracy = hit
+	} else {
+		s.Accuracy += lessonSmoothing * (hit - s.Accuracy)
+	}
+	if latency > 0 && latency < maxKeystrokeLatency {
+		if s.Latency == 0 {
+			s.Latency = latency
+		} else {
+			s.Latency += time.Duration(lessonSmoothing * float64(latency-s.Latency))
+		}
+	}
+	s.Samples++
+}
+
+// Lesson is the progress through the letters of a word list, which are
+// unlocked in order of frequency. Each list and layout has a lesson of its
+// own, since a new layout is learned from the start.
+type Lesson struct {
+	List     string
+	Layout   string
+	Letters  string
+	Unlocked int
+	Keys     map[string]*LetterStats
+	Updated  time.Time
+}
+
+// NewLesson starts a lesson on list and layout with the most frequent letters
+// of wb unlocked.
+func NewLesson(list, layout string, wb *WordBank) *Lesson {
+	counts := make(map[rune]int)
+	for _, word := range wb.Words {
+		for _, char := range strings.ToLower(word.Text) {
+			if unicode.IsLetter(char) {
+				counts[char] += word.Frequency
+			}
+		}
+	}
+
+	letters := make([]rune, 0, len(counts))
+	for char := range counts {
+		letters = append(letters, char)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if counts[letters[i]] != counts[letters[j]] {
+			return counts[letters[i]] > counts[letters[j]]
+		}
+		return letters[i] < letters[j]
+	})
+
+	return &Lesson{
+		List:     list,
+		Layout:   LayoutName(layout),
+		Letters:  string(letters),
+		Unlocked: min(lessonStartLetters, len(letters)),
+		Keys:     make(map[string]*LetterStats),
+	}
+}
+
+// lessonPath names lesson files after the word list, without the extension
+// of lists loaded from a file, and the layout unless it is QWERTY.
+func lessonPath(list, layout string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(filepath.Base(list), filepath.Ext(list))
+	if layout := LayoutName(layout); layout != QWERTY {
+		name += "-" + layout
+	}
+	return filepath.Join(dir, "lesson-"+name+".json"), nil
+}
+
+// LoadLesson reads the saved lesson of a word list and layout, or starts a new
+// one from wb, the list's words. A saved lesson whose letters no longer match
+// the list starts over, keeping the statistics of its letters.
+func LoadLesson(list, layout string, wb *WordBank) (*Lesson, error) {
+	path, err := lessonPath(list, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewLesson(list, layout, wb), nil
+		}
+		return nil, fmt.Errorf("failed to read lesson: %w", err)
+	}
+
+	lesson := &Lesson{Keys: make(map[string]*LetterStats)}
+	if err := json.Unmarshal(content, lesson); err != nil {
+		return nil, fmt.Errorf("failed to parse lesson: %w", err)
+	}
+
+	fresh := NewLesson(list, layout, wb)
+	if lesson.Letters != fresh.Letters {
+		if lesson.Keys != nil {
+			fresh.Keys = lesson.Keys
+		}
+		return fresh, nil
+	}
+	lesson.List, lesson.Layout = list, LayoutName(layout)
+	lesson.Unlocked = max(0, min(lesson.Unlocked, len([]rune(lesson.Letters))))
+	if lesson.Keys == nil {
+		lesson.Keys = make(map[string]*LetterStats)
+	}
+	return lesson, nil
+}
+
+func (l *Lesson) Save() error {
+	path, err := lessonPath(l.List, l.Layout)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	l.Updated = time.Now()
+	content, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to save lesson: %w", err)
+	}
+	return nil
+}
+
+// UnlockedLetters returns the letters that lessons practice so far.
+func (l *Lesson) UnlockedLetters() string {
+	return string([]rune(l.Letters)[:l.Unlocked])
+}
+
+// Stats returns the statistics of letter, which are empty until it has been
+// typed.
+func (l *Lesson) Stats(letter rune) *LetterStats {
+	if stats, ok := l.Keys[string(letter)]; ok {
+		return stats
+	}
+	return &LetterStats{}
+}
+
+// Focus returns the unlocked letter furthest from the targets, which lessons
+// practice the most.
+func (l *Lesson) Focus() rune {
+	var focus rune
+	worst := math.Inf(1)
+	for _, letter := range l.UnlockedLetters() {
+		stats := l.Stats(letter)
+		score := math.Min(stats.WPM()/LessonTargetWPM, stats.Accuracy/LessonTargetAccuracy)
+		if stats.Samples < lessonMinSamples {
+			score = math.Min(score, float64(stats.Samples)/lessonMinSamples)
+		}
+		if score < worst {
+			focus, worst = letter, score
+		}
+	}
+	return focus
+}
+
+// Record updates the letter statistics with the keystrokes of a test and
+// unlocks the next letter once every unlocked letter reaches the targets. It
+// returns the letter that was unlocked, or zero.
+func (l *Lesson) Record(keystrokes []game.Keystroke) rune {
+	unlocked := l.UnlockedLetters()
+	for _, k := range keystrokes {
+		expected := unicode.ToLower(k.Expected)
+		if k.Expected == 0 || !strings.ContainsRune(unlocked, expected) {
+			continue
+		}
+
+		key := string(expected)
+		if l.Keys[key] == nil {
+			l.Keys[key] = &LetterStats{}
+		}
+		l.Keys[key].add(k.Typed == k.Expected, k.Latency)
+	}
+
+	letters := []rune(l.Letters)
+	if l.Unlocked >= len(letters) {
+		return 0
+	}
+	for _, letter := range unlocked {
+		if !l.Stats(letter).Ready() {
+			return 0
+		}
+	}
+
+	l.Unlocked++
+	return letters[l.Unlocked-1]
+}
+
+// Words generates the pseudo-words of a lesson test from the unlocked
+// letters, favouring words with the focus letter.
+func (l *Lesson) Words(model *LetterModel, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	allowed := l.UnlockedLetters()
+	focus := l.Focus()
+
+	words := make([]string, lessonWords)
+	for i := range words {
+		for range focusAttempts {
+			words[i] = model.pseudoWord(rng, allowed)
+			if strings.ContainsRune(words[i], focus) {
+				break
+			}
+		}
+	}
+	return words
+}
+
+// letterCount is a possible next letter and how often it follows a state.
+// The zero letter ends a word.
+type letterCount struct {
+	Letter rune
+	Count  int
+}
+
+// LetterModel holds letter transition statistics of a word list, keyed by
+// up to two preceding letters.
+type LetterModel struct {
+	transitions map[string][]letterCount
+}
+
+// TrainLetterModel derives letter statistics from the words of wb. Common
+// words are weighed logarithmically so they do not swamp the statistics.
+func TrainLetterModel(wb *WordBank) *LetterModel {
+	counts := make(map[string]map[rune]int)
+	add := func(state string, letter rune, weight int) {
+		if counts[state] == nil {
+			counts[state] = make(map[rune]int)
+		}
+		counts[state][letter] += weight
+	}
+
+	for _, word := range wb.Words {
+		text := []rune(strings.ToLower(word.Text))
+		if strings.IndexFunc(string(text), func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+			continue
+		}
+
+		weight := 1 + int(math.Log(float64(word.Frequency)))
+		for i := 0; i <= len(text); i++ {
+			var next rune
+			if i < len(text) {
+				next = text[i]
+			}
+			// Near the start of a word the one letter state is the only one
+			add(string(text[max(0, i-2):i]), next, weight)
+			if i >= 2 {
+				add(string(text[i-1:i]), next, weight)
+			}
+		}
+	}
+
+	model := &LetterModel{transitions: make(map[string][]letterCount)}
+	for state, next := range counts {
+		list := make([]letterCount, 0, len(next))
+		for letter, count := range next {
+			list = append(list, letterCount{letter, count})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Letter < list[j].Letter })
+		model.transitions[state] = list
+	}
+	return model
+}
+
+// pseudoWord builds a pronounceable word from the allowed letters, backing
+// off to shorter states and then to a uniform choice when the statistics
+// have nothing to offer.
+func (m *LetterModel) pseudoWord(rng *rand.Rand, allowed string) string {
+	letters := []rune(allowed)
+	if len(letters) == 0 {
+		return ""
+	}
+
+	var word []rune
+	for len(word) < maxPseudoLength {
+		var candidates []letterCount
+		for back := min(2, len(word)); back >= 0 && candidates == nil; back-- {
+			if back == 0 && len(word) > 0 {
+				break
+			}
+			for _, c := range m.transitions[string(word[len(word)-back:])] {
+				end := c.Letter == 0 && len(word) >= minPseudoLength
+				if end || (c.Letter != 0 && strings.ContainsRune(allowed, c.Letter)) {
+					candidates = append(candidates, c)
+				}
+			}
+		}
+
+		if candidates == nil {
+			if len(word) >= minPseudoLength {
+				break
+			}
+			word = append(word, letters[rng.Intn(len(letters))])
+			continue
+		}
+
+		total := 0
+		for _, c := range candidates {
+			total += c.Count
+		}
+		target := rng.Intn(total)
+		var next rune
+		for _, c := range candidates {
+			if target < c.Count {
+				next = c.Letter
+				break
+			}
+			target -= c.Count
+		}
+		if next == 0 {
+			break
+		}
+		word = append(word, next)
+	}
+	return string(word)
+}
@@ -0,0 +1,234 @@
+package data
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const DefaultMarkovOrder = 2
+
+// markovCacheVersion changes whenever training changes, so that models
+// cached by older versions are trained again.
+const markovCacheVersion = 2
+
+// MarkovModel is a word-level n-gram model. Transitions maps the previous
+// Order words, joined by spaces, to the words that followed them in the
+// corpus and how often they did.
+type MarkovModel struct {
+	Order       int
+	Transitions map[string][]Word
+	Starts      []string
+}
+
+// TrainMarkov builds a model from the text in r. Sentences are split on
+// terminal punctuation so that generated text starts like real sentences.
+func TrainMarkov(r io.Reader, order int) (*MarkovModel, error) {
+	if order < 1 {
+		return nil, fmt.Errorf("markov order must be at least 1, got %d", order)
+	}
+
+	counts := make(map[string]map[string]int)
+	starts := make(map[string]bool)
+	state := make([]string, 0, order)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		word := typeableWord(scanner.Text())
+		if word == "" {
+			continue
+		}
+
+		if len(state) == order {
+			key := strings.Join(state, " ")
+			if counts[key] == nil {
+				counts[key] = make(map[string]int)
+			}
+			counts[key][word]++
+			state = append(state[1:], word)
+		} else {
+			state = append(state, word)
+			if len(state) == order {
+				starts[strings.Join(state, " ")] = true
+			}
+		}
+
+		// A new sentence begins after terminal punctuation
+		if strings.ContainsAny(word[len(word)-1:], ".?!") {
+			state = state[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+
+	model := &MarkovModel{
+		Order:       order,
+		Transitions: make(map[string][]Word, len(counts)),
+	}
+	for key, next := range counts {
+		words := make([]Word, 0, len(next))
+		for text, count := range next {
+			words = append(words, Word{Text: text, Frequency: count})
+		}
+		// Sorted so that a seed always generates the same text
+		sort.Slice(words, func(i, j int) bool { return words[i].Text < words[j].Text })
+		model.Transitions[key] = words
+	}
+	for start := range starts {
+		if _, ok := model.Transitions[start]; ok {
+			model.Starts = append(model.Starts, start)
+		}
+	}
+	sort.Strings(model.Starts)
+
+	if len(model.Starts) == 0 {
+		return nil, fmt.Errorf("corpus is too short to train a model of order %d", order)
+	}
+	return model, nil
+}
+
+// typeableWord converts typographic quotes and dashes to ASCII and returns
+// "" for words that still have characters that cannot be typed in a test.
+// Such words are skipped rather than trimmed, which would turn "café" into a
+// word that does not exist.
+func typeableWord(word string) string {
+	word = asciiReplacer.Replace(word)
+	for _, char := range word {
+		if char <= 32 || char > 126 {
+			return ""
+		}
+	}
+	return word
+}
+
+// LoadMarkov trains a model from the corpus file at path, reusing a cached
+// model from the data directory when the corpus has not changed.
+func LoadMarkov(path string, order int) (*MarkovModel, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %w", err)
+	}
+
+	cachePath := ""
+	if dir, err := DataDir(); err == nil {
+		absPath, _ := filepath.Abs(path)
+		key := fmt.Sprintf("%s|%d|%d|%d|%d", absPath, info.Size(), info.ModTime().UnixNano(), order, markovCacheVersion)
+		cachePath = filepath.Join(dir, "cache", fmt.Sprintf("markov-%x.gob", sha256.Sum256([]byte(key))))
+
+		if model, err := readMarkovCache(cachePath); err == nil {
+			return model, nil
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %w", err)
+	}
+	defer file.Close()
+
+	model, err := TrainMarkov(file, order)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cache only saves time, so failing to write it is not an error
+	if cachePath != "" {
+		writeMarkovCache(cachePath, model)
+	}
+	return model, nil
+}
+
+func readMarkovCache(path string) (*MarkovModel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var model MarkovModel
+	if err := gob.NewDecoder(file).Decode(&model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+func writeMarkovCache(path string, model *MarkovModel) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return gob.NewEncoder(file).Encode(model)
+}
+
+// MarkovGenerator produces an endless stream of words from a model and
+// implements game.WordSource.
+type MarkovGenerator struct {
+	model   *MarkovModel
+	rng     *rand.Rand
+	state   []string
+	pending []string
+}
+
+func (m *MarkovModel) NewGenerator(seed int64) *MarkovGenerator {
+	return &MarkovGenerator{
+		model: m,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (g *MarkovGenerator) Next() (string, bool) {
+	if len(g.model.Starts) == 0 {
+		return "", false
+	}
+
+	if len(g.pending) == 0 {
+		g.advance()
+	}
+	word := g.pending[0]
+	g.pending = g.pending[1:]
+	return word, true
+}
+
+// advance extends the chain by one word, or starts a new sentence at the
+// beginning and whenever the chain has no continuation.
+func (g *MarkovGenerator) advance() {
+	next := g.model.Transitions[strings.Join(g.state, " ")]
+	if len(g.state) == 0 || len(next) == 0 {
+		start := g.model.Starts[g.rng.Intn(len(g.model.Starts))]
+		g.state = strings.Fields(start)
+		g.pending = append(g.pending, g.state...)
+		return
+	}
+
+	total := 0
+	for _, word := range next {
+		total += word.Frequency
+	}
+	target := g.rng.Intn(total)
+	word := next[len(next)-1].Text
+	for _, candidate := range next {
+		target -= candidate.Frequency
+		if target < 0 {
+			word = candidate.Text
+			break
+		}
+	}
+
+	g.state = append(g.state[1:], word)
+	g.pending = append(g.pending, word)
+}
@@ -0,0 +1,20 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DataDir returns the directory where typr keeps user data such as custom
+// word lists. It follows $XDG_DATA_HOME and falls back to ~/.local/share.
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "typr"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "typr"), nil
+}
@@ -0,0 +1,105 @@
+package data
+
+import (
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GenerateOptions controls what is injected into generated word sequences.
+type GenerateOptions struct {
+	Punctuation bool
+	Numbers     bool
+}
+
+// minSentence is the shortest sentence the generator ends with a period.
+const minSentence = 3
+
+// Rates at which punctuation and numbers are injected, per word
+const (
+	numberRate   = 0.10
+	quoteRate    = 0.03
+	parenRate    = 0.02
+	hyphenRate   = 0.02
+	periodRate   = 0.08
+	questionRate = 0.02
+	commaRate    = 0.08
+)
+
+// SentenceGenerator turns words drawn from a bank into sentence-like text.
+// Sentences start with a capital letter and end with a period or question
+// mark. All random choices use the bank's seeded source.
+type SentenceGenerator struct {
+	bank       *WordBank
+	options    GenerateOptions
+	capitalize bool
+	length     int // words in the current sentence
+}
+
+func (wb *WordBank) NewSentenceGenerator(options GenerateOptions) *SentenceGenerator {
+	return &SentenceGenerator{
+		bank:       wb,
+		options:    options,
+		capitalize: true,
+	}
+}
+
+// Next implements game.WordSource.
+func (g *SentenceGenerator) Next() (string, bool) {
+	word, ok := g.bank.Next()
+	if !ok {
+		return "", false
+	}
+	rng := g.bank.random()
+
+	if g.options.Numbers && rng.Float64() < numberRate {
+		// Mostly short numbers, occasionally years and larger amounts
+		digits := 1 + rng.Intn(4)
+		limit := 1
+		for range digits {
+			limit *= 10
+		}
+		word = strconv.Itoa(rng.Intn(limit))
+	}
+
+	if !g.options.Punctuation {
+		return word, true
+	}
+
+	if g.capitalize {
+		word = capitalize(word)
+		g.capitalize = false
+	}
+	g.length++
+
+	switch r := rng.Float64(); {
+	case r < quoteRate:
+		word = "\"" + word + "\""
+	case r < quoteRate+parenRate:
+		word = "(" + word + ")"
+	case r < quoteRate+parenRate+hyphenRate:
+		word += "-" + g.bank.SelectRandomWord()
+	}
+
+	switch r := rng.Float64(); {
+	case g.length < minSentence:
+	case r < periodRate:
+		word += "."
+		g.capitalize, g.length = true, 0
+	case r < periodRate+questionRate:
+		word += "?"
+		g.capitalize, g.length = true, 0
+	case r < periodRate+questionRate+commaRate:
+		word += ","
+	}
+
+	return word, true
+}
+
+func capitalize(word string) string {
+	first, size := utf8.DecodeRuneInString(word)
+	if first == utf8.RuneError {
+		return word
+	}
+	return string(unicode.ToUpper(first)) + word[size:]
+}
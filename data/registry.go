@@ -0,0 +1,290 @@
+package data
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const DefaultWordList = "english"
+
+//go:embed wordlists/*.txt
+var builtinWordLists embed.FS
+
+// WordList describes a word list file. Name and language are read from
+// "# name:" and "# language:" comment lines at the top of the file.
+type WordList struct {
+	ID       string
+	Name     string
+	Language string
+	Size     int
+	Path     string
+	Builtin  bool
+
+	// fsys is set for lists embedded in the binary
+	fsys fs.FS
+}
+
+func (l WordList) String() string {
+	return fmt.Sprintf("%s (%s, %d words)", l.Name, l.Language, l.Size)
+}
+
+// Open opens the list's file, either embedded or on disk.
+func (l WordList) Open() (io.ReadCloser, error) {
+	if l.fsys == nil {
+		return os.Open(l.Path)
+	}
+	return l.fsys.Open(l.Path)
+}
+
+func (l WordList) Load() (*WordBank, error) {
+	file, err := l.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open words file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadWords(file)
+}
+
+type Registry struct {
+	Lists []WordList
+
+	// Skipped are the files in a word list directory that could not be read
+	Skipped []SkippedList
+}
+
+// SkippedList is a word list file left out of the registry, and why.
+type SkippedList struct {
+	Path string
+	Err  error
+}
+
+// UserWordListDir returns the directory for user-provided word lists.
+func UserWordListDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wordlists"), nil
+}
+
+// DiscoverWordLists finds the word lists embedded in the binary and those in
+// the user's data directory. A user list replaces a built-in list with the same ID.
+func DiscoverWordLists() (*Registry, error) {
+	registry := &Registry{}
+
+	if err := registry.addFS(builtinWordLists, "wordlists"); err != nil {
+		return nil, err
+	}
+
+	if dir, err := UserWordListDir(); err == nil {
+		if err := registry.AddDir(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// wordListExts are the extensions of word list files in a directory.
+var wordListExts = []string{".txt", ".json"}
+
+// AddDir registers every word list file in dir. Missing directories are
+// ignored, and files that cannot be read are added to Skipped.
+func (r *Registry) AddDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read word list directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(wordListExts, filepath.Ext(entry.Name())) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if _, err := r.AddFile(path); err != nil {
+			r.Skipped = append(r.Skipped, SkippedList{Path: path, Err: err})
+		}
+	}
+
+	return nil
+}
+
+// AddFile registers a single word list file and returns its description.
+func (r *Registry) AddFile(path string) (WordList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return WordList{}, fmt.Errorf("failed to open words file: %w", err)
+	}
+	defer file.Close()
+
+	list, err := readWordListInfo(file, path)
+	if err != nil {
+		return list, err
+	}
+	r.add(list)
+	return list, nil
+}
+
+func (r *Registry) addFS(fsys fs.FS, dir string) error {
+	paths, err := fs.Glob(fsys, dir+"/*.txt")
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		file, err := fsys.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open words file: %w", err)
+		}
+		list, err := readWordListInfo(file, path)
+		file.Close()
+		if err != nil {
+			return err
+		}
+
+		list.Builtin = true
+		list.fsys = fsys
+		r.add(list)
+	}
+
+	return nil
+}
+
+// ResolveDefault picks the word list to start with. The first of these that
+// is set wins: the -words flag, the config file, a words.txt in the user data
+// directory, and finally the embedded English list. Flag and config values
+// may be either a list ID or a path to a word list file.
+func (r *Registry) ResolveDefault(flagValue string, config Config) (string, error) {
+	for _, value := range []string{flagValue, config.WordList} {
+		if value == "" {
+			continue
+		}
+		if _, ok := r.Find(value); ok {
+			return value, nil
+		}
+		if _, err := os.Stat(value); err != nil {
+			return "", fmt.Errorf("word list not found: %s", value)
+		}
+		list, err := r.AddFile(value)
+		if err != nil {
+			return "", err
+		}
+		return list.ID, nil
+	}
+
+	if dir, err := DataDir(); err == nil {
+		path := filepath.Join(dir, "words.txt")
+		if _, err := os.Stat(path); err == nil {
+			list, err := r.AddFile(path)
+			if err != nil {
+				return "", err
+			}
+			return list.ID, nil
+		}
+	}
+
+	if _, ok := r.Find(DefaultWordList); ok {
+		return DefaultWordList, nil
+	}
+	if len(r.Lists) == 0 {
+		return "", fmt.Errorf("no word lists found")
+	}
+	return r.Lists[0].ID, nil
+}
+
+func (r *Registry) add(list WordList) {
+	for i, existing := range r.Lists {
+		if existing.ID == list.ID {
+			r.Lists[i] = list
+			return
+		}
+	}
+
+	r.Lists = append(r.Lists, list)
+	sort.Slice(r.Lists, func(i, j int) bool {
+		return r.Lists[i].ID < r.Lists[j].ID
+	})
+}
+
+func (r *Registry) Find(id string) (WordList, bool) {
+	for _, list := range r.Lists {
+		if list.ID == id {
+			return list, true
+		}
+	}
+	return WordList{}, false
+}
+
+func readWordListInfo(r io.Reader, path string) (WordList, error) {
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	list := WordList{
+		ID:       id,
+		Name:     id,
+		Language: "unknown",
+		Path:     path,
+	}
+
+	reader := bufio.NewReaderSize(r, detectSize)
+	if DetectFormat(reader) == FormatJSON {
+		return readJSONWordListInfo(reader, list)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if comment, ok := strings.CutPrefix(line, "#"); ok {
+			key, value, found := strings.Cut(comment, ":")
+			if !found {
+				continue
+			}
+			switch strings.TrimSpace(strings.ToLower(key)) {
+			case "name":
+				list.Name = strings.TrimSpace(value)
+			case "language":
+				list.Language = strings.TrimSpace(value)
+			}
+			continue
+		}
+
+		list.Size++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return list, fmt.Errorf("failed to read words file: %w", err)
+	}
+	return list, nil
+}
+
+// readJSONWordListInfo takes the name of a monkeytype list from the file. Its
+// language is the part of the name before any size suffix, as in english_1k.
+func readJSONWordListInfo(r io.Reader, list WordList) (WordList, error) {
+	var info monkeytypeList
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		return list, fmt.Errorf("failed to read words file %s: %w", list.Path, err)
+	}
+
+	if info.Name != "" {
+		list.Name = info.Name
+		list.Language, _, _ = strings.Cut(info.Name, "_")
+	}
+	list.Size = len(info.Words)
+	return list, nil
+}
@@ -0,0 +1,246 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"typr/game"
+	"unicode"
+)
+
+// Review grades on the SM-2 scale of 0 to 5.
+const (
+	gradeCorrect = 4
+	gradeWrong   = 1
+)
+
+const (
+	defaultEase = 2.5
+	minEase     = 1.3
+
+	// reviewEvery is how many generated words separate two review words when
+	// they are mixed into a test.
+	reviewEvery = 4
+)
+
+// ReviewItem is a mistyped word scheduled for review with SM-2. Interval is
+// the number of days until the word is due again.
+type ReviewItem struct {
+	Word        string
+	Repetitions int
+	Interval    int
+	Ease        float64
+	Due         time.Time
+	Lapses      int
+}
+
+// ReviewQueue holds the scheduled words by text.
+type ReviewQueue struct {
+	Items map[string]*ReviewItem
+}
+
+func NewReviewQueue() *ReviewQueue {
+	return &ReviewQueue{Items: make(map[string]*ReviewItem)}
+}
+
+func reviewPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "review.json"), nil
+}
+
+// LoadReviewQueue reads the saved queue. A missing file yields an empty
+// queue.
+func LoadReviewQueue() (*ReviewQueue, error) {
+	path, err := reviewPath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewReviewQueue(), nil
+		}
+		return nil, fmt.Errorf("failed to read review queue: %w", err)
+	}
+
+	queue := NewReviewQueue()
+	if err := json.Unmarshal(content, queue); err != nil {
+		return nil, fmt.Errorf("failed to parse review queue: %w", err)
+	}
+	return queue, nil
+}
+
+func (q *ReviewQueue) Save() error {
+	path, err := reviewPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	content, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to save review queue: %w", err)
+	}
+	return nil
+}
+
+// Review schedules word after it was typed. Words typed correctly are only
+// rescheduled if they are queued and due, while mistyped words are added or
+// lapse whenever they are missed.
+func (q *ReviewQueue) Review(word string, correct bool, now time.Time) {
+	item, ok := q.Items[word]
+	if !ok {
+		if correct {
+			return
+		}
+		item = &ReviewItem{Word: word, Ease: defaultEase}
+		q.Items[word] = item
+	} else if correct && item.Due.After(now) {
+		return
+	}
+
+	grade := gradeCorrect
+	if !correct {
+		grade = gradeWrong
+	}
+
+	if grade >= 3 {
+		switch item.Repetitions {
+		case 0:
+			item.Interval = 1
+		case 1:
+			item.Interval = 6
+		default:
+			item.Interval = int(math.Round(float64(item.Interval) * item.Ease))
+		}
+		item.Repetitions++
+	} else {
+		// Unlike plain SM-2, a lapse is due again straight away so that the
+		// next review test picks it up
+		item.Repetitions = 0
+		item.Interval = 0
+		item.Lapses++
+	}
+
+	miss := float64(5 - grade)
+	item.Ease = math.Max(minEase, item.Ease+0.1-miss*(0.08+miss*0.02))
+	item.Due = now.AddDate(0, 0, item.Interval)
+}
+
+// RecordTest reviews every word completed in a test once. A word that
+// appeared several times counts as correct only if it was never mistyped.
+// Code tests, lessons and tests with modifiers are skipped, since their words
+// are not plain words.
+func (q *ReviewQueue) RecordTest(g *game.GameState, now time.Time) {
+	if g.CodeMode || g.Lesson || len(g.Modifiers) > 0 {
+		return
+	}
+
+	var words []string
+	correct := make(map[string]bool)
+	for i, typed := range g.History {
+		if i >= len(g.Words) {
+			break
+		}
+		word := reviewWord(g.Words[i])
+		if word == "" {
+			continue
+		}
+		if _, seen := correct[word]; !seen {
+			words = append(words, word)
+			correct[word] = true
+		}
+		correct[word] = correct[word] && typed == g.Words[i]
+	}
+
+	for _, word := range words {
+		q.Review(word, correct[word], now)
+	}
+}
+
+// reviewWord strips the punctuation and capitals that word generation adds to
+// a word. Words without letters are not worth reviewing.
+func reviewWord(word string) string {
+	word = strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	if strings.IndexFunc(word, unicode.IsLetter) < 0 {
+		return ""
+	}
+	return strings.ToLower(word)
+}
+
+// Due returns the words due for review at now, most overdue first.
+func (q *ReviewQueue) Due(now time.Time) []string {
+	var items []*ReviewItem
+	for _, item := range q.Items {
+		if !item.Due.After(now) {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Due.Equal(items[j].Due) {
+			return items[i].Due.Before(items[j].Due)
+		}
+		return items[i].Word < items[j].Word
+	})
+
+	words := make([]string, len(items))
+	for i, item := range items {
+		words[i] = item.Word
+	}
+	return words
+}
+
+// ReviewWords returns the due words in an order shuffled by seed, for a
+// dedicated review test.
+func (q *ReviewQueue) ReviewWords(now time.Time, seed int64) []string {
+	words := q.Due(now)
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(words), func(i, j int) {
+		words[i], words[j] = words[j], words[i]
+	})
+	return words
+}
+
+// reviewSource mixes due words into another source.
+type reviewSource struct {
+	source game.WordSource
+	due    []string
+	count  int
+}
+
+// MixReview returns a source that inserts one of the review words after
+// every few words of source, until each of them has appeared once.
+func MixReview(source game.WordSource, words []string) game.WordSource {
+	if len(words) == 0 {
+		return source
+	}
+	return &reviewSource{source: source, due: words}
+}
+
+func (r *reviewSource) Next() (string, bool) {
+	r.count++
+	if r.count%(reviewEvery+1) == 0 && len(r.due) > 0 {
+		word := r.due[0]
+		r.due = r.due[1:]
+		return word, true
+	}
+	return r.source.Next()
+}
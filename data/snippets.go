@@ -0,0 +1,67 @@
+package data
+
+import (
+	"math/rand"
+	"strings"
+)
+
+var builtinSnippets = []string{
+	`func reverse(s []int) []int {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+	return s
+}`,
+	`func (s *Stack) Pop() (int, error) {
+	if len(s.items) == 0 {
+		return 0, errors.New("stack is empty")
+	}
+	item := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return item, nil
+}`,
+	`func wordCount(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.Fields(text) {
+		counts[strings.ToLower(word)]++
+	}
+	return counts
+}`,
+	`def fibonacci(n):
+    a, b = 0, 1
+    for _ in range(n):
+        yield a
+        a, b = b, a + b`,
+	`class Counter:
+    def __init__(self, start=0):
+        self.value = start
+
+    def increment(self, step=1):
+        self.value += step
+        return self.value`,
+	`function debounce(fn, delay) {
+	let timer = null;
+	return (...args) => {
+		clearTimeout(timer);
+		timer = setTimeout(() => fn(...args), delay);
+	};
+}`,
+	`const sum = (xs) => xs.reduce((acc, x) => acc + x, 0);
+const mean = (xs) => (xs.length === 0 ? 0 : sum(xs) / xs.length);`,
+	`fn max_of(values: &[i32]) -> Option<i32> {
+    let mut best = *values.first()?;
+    for &v in values.iter() {
+        if v > best {
+            best = v;
+        }
+    }
+    Some(best)
+}`,
+}
+
+// RandomSnippet returns the lines of a built-in code snippet chosen by seed.
+func RandomSnippet(seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	snippet := builtinSnippets[rng.Intn(len(builtinSnippets))]
+	return strings.Split(snippet, "\n")
+}
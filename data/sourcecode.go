@@ -0,0 +1,235 @@
+package data
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Snippet size limits in lines
+const (
+	minSnippetLines = 3
+	maxSnippetLines = 30
+)
+
+// Snippet is a function-sized chunk of a source file.
+type Snippet struct {
+	Path  string
+	Line  int
+	Lines []string
+}
+
+func (s Snippet) String() string {
+	return fmt.Sprintf("%s:%d", s.Path, s.Line)
+}
+
+var (
+	skippedDirs = []string{"node_modules", "vendor", "target", "dist", "build", "__pycache__"}
+
+	// blockStart matches lines that typically open a function or type
+	blockStart = regexp.MustCompile(`^\s*(export\s+)?(pub(\(\w+\))?\s+)?(async\s+)?(def|class|function|func|fn|impl|struct|interface|enum|public|private|protected|static|type)\b`)
+)
+
+// CollectSnippets walks dir and extracts snippets from every file with one
+// of the given extensions. Go files are split into declarations with
+// go/parser, other languages with indentation and brace heuristics.
+func CollectSnippets(dir string, exts []string) ([]Snippet, error) {
+	var snippets []Snippet
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != dir && (strings.HasPrefix(name, ".") || slices.Contains(skippedDirs, name)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if !slices.Contains(exts, ext) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read source file: %w", err)
+		}
+
+		var found []Snippet
+		if ext == ".go" {
+			found = goSnippets(path, content)
+		}
+		if found == nil {
+			found = heuristicSnippets(path, string(content))
+		}
+		snippets = append(snippets, found...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(snippets) == 0 {
+		return nil, fmt.Errorf("no code snippets found in %s for %s", dir, strings.Join(exts, ", "))
+	}
+	return snippets, nil
+}
+
+// ParseExtensions turns a comma separated list like "go,.py" into
+// extensions with a leading dot.
+func ParseExtensions(list string) []string {
+	var exts []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// goSnippets returns the top-level function and type declarations of a Go
+// file, including their doc comments. It returns nil if the file does not
+// parse.
+func goSnippets(path string, content []byte) []Snippet {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(string(content), "\n")
+	snippets := []Snippet{}
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+
+		first := fset.Position(start).Line
+		last := fset.Position(decl.End()).Line
+		if snippet, ok := newSnippet(path, lines, first, last); ok {
+			snippets = append(snippets, snippet)
+		}
+	}
+	return snippets
+}
+
+// heuristicSnippets finds blocks that start at a line matching blockStart.
+// Blocks opened by a brace end where the braces balance again; other blocks
+// end at the first line indented no deeper than the start.
+func heuristicSnippets(path, content string) []Snippet {
+	lines := strings.Split(content, "\n")
+	var snippets []Snippet
+
+	for i := 0; i < len(lines); i++ {
+		if !blockStart.MatchString(lines[i]) {
+			continue
+		}
+
+		end := blockEnd(lines, i)
+		if snippet, ok := newSnippet(path, lines, i+1, end+1); ok {
+			snippets = append(snippets, snippet)
+			i = end
+		}
+	}
+
+	// Files without recognizable blocks are cut into fixed windows
+	if len(snippets) == 0 {
+		for i := 0; i < len(lines); i += maxSnippetLines / 2 {
+			end := min(i+maxSnippetLines/2, len(lines))
+			if snippet, ok := newSnippet(path, lines, i+1, end); ok {
+				snippets = append(snippets, snippet)
+			}
+		}
+	}
+	return snippets
+}
+
+// blockEnd returns the index of the last line of the block starting at start.
+func blockEnd(lines []string, start int) int {
+	depth := 0
+	opened := false
+	indent := indentation(lines[start])
+
+	for i := start; i < len(lines) && i < start+maxSnippetLines*2; i++ {
+		line := lines[i]
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if strings.Contains(line, "{") {
+			opened = true
+		}
+
+		if opened && depth <= 0 {
+			return i
+		}
+		if !opened && i > start && strings.TrimSpace(line) != "" && indentation(line) <= indent {
+			// The block ended on the previous non-empty line
+			end := i - 1
+			for end > start && strings.TrimSpace(lines[end]) == "" {
+				end--
+			}
+			return end
+		}
+	}
+	return min(start+maxSnippetLines, len(lines)) - 1
+}
+
+func indentation(line string) int {
+	expanded := strings.ReplaceAll(line, "\t", "    ")
+	return len(expanded) - len(strings.TrimLeft(expanded, " "))
+}
+
+// newSnippet builds a snippet from the 1-based inclusive line range, and
+// reports false if it is too short or too long to type.
+func newSnippet(path string, lines []string, first, last int) (Snippet, bool) {
+	if first < 1 || last > len(lines) || first > last {
+		return Snippet{}, false
+	}
+
+	snippetLines := make([]string, 0, last-first+1)
+	for _, line := range lines[first-1 : last] {
+		snippetLines = append(snippetLines, strings.TrimRight(line, " \t\r"))
+	}
+
+	for len(snippetLines) > 0 && snippetLines[len(snippetLines)-1] == "" {
+		snippetLines = snippetLines[:len(snippetLines)-1]
+	}
+
+	count := len(snippetLines)
+	if count < minSnippetLines || count > maxSnippetLines {
+		return Snippet{}, false
+	}
+	return Snippet{Path: path, Line: first, Lines: snippetLines}, true
+}
+
+// PickSnippet chooses a snippet by seed.
+func PickSnippet(snippets []Snippet, seed int64) Snippet {
+	rng := rand.New(rand.NewSource(seed))
+	return snippets[rng.Intn(len(snippets))]
+}
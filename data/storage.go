@@ -1,18 +1,62 @@
 package data
 
 import (
+	"bufio"
 	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 	"typr/game"
 )
 
 const StatsFileName = "stats.csv"
 
+// StatsVersion is the version of the stats file schema. Version 1 files have
+// no header and hold the columns in statsColumns order, either the first
+// seven or ten of them. From version 2 on, a version line and a header row
+// name the columns, so columns can be added without breaking older files.
+// Version 3 added the text generation options and word filter.
+const StatsVersion = 3
+
+const statsVersionPrefix = "# typr stats v"
+
+var statsColumns = []string{
+	"timestamp", "wpm", "accuracy", "duration", "words", "errors", "chars",
+	"modifiers", "wordlist", "seed", "punctuation", "numbers", "filter",
+}
+
+// statsFile is the content of a stats file with its column names.
+type statsFile struct {
+	version int
+	header  []string
+	records [][]string
+	// noHeader is set for a versioned file that ends after its version line
+	noHeader bool
+}
+
 func SaveTestResult(result game.TestResult) error {
-	file, err := os.OpenFile(StatsFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	stats, err := readStatsFile(StatsFileName, true)
+	if err != nil {
+		return err
+	}
+	if stats.version > 0 && stats.version < StatsVersion && !stats.noHeader {
+		if err := MigrateStats(); err != nil {
+			return err
+		}
+		stats.header = statsColumns
+	}
+
+	// A file without rows is started over with the current header
+	fresh := stats.version == 0 || stats.noHeader
+	flags := os.O_APPEND | os.O_CREATE | os.O_WRONLY
+	if fresh {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(StatsFileName, flags, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open stats file: %w", err)
 	}
@@ -21,86 +65,224 @@ func SaveTestResult(result game.TestResult) error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	record := []string{
-		result.Timestamp.Format(time.RFC3339),
-		fmt.Sprintf("%.2f", result.WPM),
-		fmt.Sprintf("%.2f", result.Accuracy),
-		result.TestDuration.String(),
-		strconv.Itoa(result.TotalWords),
-		strconv.Itoa(result.Errors),
-		strconv.Itoa(result.TotalChars),
+	if fresh {
+		if err := writeStatsHeader(file, writer); err != nil {
+			return err
+		}
+		stats.header = statsColumns
 	}
 
+	// Rows follow the file's header, which a newer version may have extended
+	fields := encodeResult(result)
+	record := make([]string, len(stats.header))
+	for i, column := range stats.header {
+		record[i] = fields[column]
+	}
 	return writer.Write(record)
 }
 
-func LoadTestResults() ([]game.TestResult, error) {
-	file, err := os.Open(StatsFileName)
+func writeStatsHeader(w io.Writer, writer *csv.Writer) error {
+	if _, err := fmt.Fprintf(w, "%s%d\n", statsVersionPrefix, StatsVersion); err != nil {
+		return fmt.Errorf("failed to write stats header: %w", err)
+	}
+	return writer.Write(statsColumns)
+}
+
+// MigrateStats rewrites a stats file of an older version with the current
+// version line and header row, keeping the original next to it as a backup.
+// Files that are missing or already current are left alone.
+func MigrateStats() error {
+	stats, err := readStatsFile(StatsFileName, false)
+	if err != nil || stats.version == 0 || stats.version >= StatsVersion || stats.noHeader {
+		return err
+	}
+
+	tmpName := StatsFileName + ".tmp"
+	file, err := os.Create(tmpName)
+	if err != nil {
+		return fmt.Errorf("failed to migrate stats file: %w", err)
+	}
+	defer os.Remove(tmpName)
+
+	// Values move to the current column of the same name, and rows that fail
+	// to parse are kept with any values past the header at the end
+	writer := csv.NewWriter(file)
+	if err := writeStatsHeader(file, writer); err != nil {
+		file.Close()
+		return err
+	}
+	for _, record := range stats.records {
+		migrated := make([]string, len(statsColumns))
+		for i, value := range record {
+			column := -1
+			if i < len(stats.header) {
+				column = slices.Index(statsColumns, stats.header[i])
+			}
+			if column < 0 {
+				migrated = append(migrated, value)
+				continue
+			}
+			migrated[column] = value
+		}
+		writer.Write(migrated)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to migrate stats file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to migrate stats file: %w", err)
+	}
+
+	if err := os.Rename(StatsFileName, fmt.Sprintf("%s.v%d.bak", StatsFileName, stats.version)); err != nil {
+		return fmt.Errorf("failed to back up stats file: %w", err)
+	}
+	if err := os.Rename(tmpName, StatsFileName); err != nil {
+		return fmt.Errorf("failed to migrate stats file: %w", err)
+	}
+	return nil
+}
+
+// readStatsFile reads a stats file and works out its columns. A missing file
+// has version 0. With headerOnly set, only the version and header are read.
+func readStatsFile(path string, headerOnly bool) (*statsFile, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []game.TestResult{}, nil
+			return &statsFile{}, nil
 		}
 		return nil, fmt.Errorf("failed to open stats file: %w", err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
-	}
+	reader := bufio.NewReader(file)
+	stats := &statsFile{version: 1, header: statsColumns}
 
-	results := make([]game.TestResult, 0, len(records))
+	first, err := reader.Peek(len(statsVersionPrefix))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read stats file: %w", err)
+	}
+	if len(first) == 0 {
+		// An empty file is as good as a missing one
+		return &statsFile{}, nil
+	}
 
-	for _, record := range records {
-		if len(record) != 7 {
-			continue
+	versioned := string(first) == statsVersionPrefix
+	if versioned {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read stats file: %w", err)
 		}
-
-		timestamp, err := time.Parse(time.RFC3339, record[0])
+		stats.version, err = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, statsVersionPrefix)))
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("invalid stats file version: %s", strings.TrimSpace(line))
 		}
+	}
 
-		wpm, err := strconv.ParseFloat(record[1], 64)
-		if err != nil {
-			continue
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	if versioned {
+		stats.header, err = csvReader.Read()
+		if err == io.EOF {
+			stats.header = statsColumns
+			stats.noHeader = true
+			return stats, nil
 		}
-
-		accuracy, err := strconv.ParseFloat(record[2], 64)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("failed to read stats header: %w", err)
 		}
+	}
+	if headerOnly {
+		return stats, nil
+	}
 
-		duration, err := time.ParseDuration(record[3])
-		if err != nil {
-			continue
-		}
+	stats.records, err = csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	return stats, nil
+}
 
-		totalWords, err := strconv.Atoi(record[4])
-		if err != nil {
-			continue
-		}
+func encodeResult(result game.TestResult) map[string]string {
+	return map[string]string{
+		"timestamp":   result.Timestamp.Format(time.RFC3339),
+		"wpm":         fmt.Sprintf("%.2f", result.WPM),
+		"accuracy":    fmt.Sprintf("%.2f", result.Accuracy),
+		"duration":    result.TestDuration.String(),
+		"words":       strconv.Itoa(result.TotalWords),
+		"errors":      strconv.Itoa(result.Errors),
+		"chars":       strconv.Itoa(result.TotalChars),
+		"modifiers":   strings.Join(result.Modifiers, "+"),
+		"wordlist":    result.WordList,
+		"seed":        strconv.FormatInt(result.Seed, 10),
+		"punctuation": strconv.FormatBool(result.Punctuation),
+		"numbers":     strconv.FormatBool(result.Numbers),
+		"filter":      result.Filter,
+	}
+}
 
-		errors, err := strconv.Atoi(record[5])
-		if err != nil {
-			continue
-		}
+// decodeResult parses a row by column name. The original seven columns are
+// required, later ones are optional and columns this version does not know
+// are ignored.
+func decodeResult(fields map[string]string) (game.TestResult, bool) {
+	var result game.TestResult
+	var err error
 
-		totalChars, err := strconv.Atoi(record[6])
-		if err != nil {
-			continue
+	if result.Timestamp, err = time.Parse(time.RFC3339, fields["timestamp"]); err != nil {
+		return result, false
+	}
+	if result.WPM, err = strconv.ParseFloat(fields["wpm"], 64); err != nil {
+		return result, false
+	}
+	if result.Accuracy, err = strconv.ParseFloat(fields["accuracy"], 64); err != nil {
+		return result, false
+	}
+	if result.TestDuration, err = time.ParseDuration(fields["duration"]); err != nil {
+		return result, false
+	}
+	if result.TotalWords, err = strconv.Atoi(fields["words"]); err != nil {
+		return result, false
+	}
+	if result.Errors, err = strconv.Atoi(fields["errors"]); err != nil {
+		return result, false
+	}
+	if result.TotalChars, err = strconv.Atoi(fields["chars"]); err != nil {
+		return result, false
+	}
+
+	if fields["modifiers"] != "" {
+		result.Modifiers = strings.Split(fields["modifiers"], "+")
+	}
+	result.WordList = fields["wordlist"]
+	result.Seed, _ = strconv.ParseInt(fields["seed"], 10, 64)
+	result.Punctuation, _ = strconv.ParseBool(fields["punctuation"])
+	result.Numbers, _ = strconv.ParseBool(fields["numbers"])
+	result.Filter = fields["filter"]
+
+	return result, true
+}
+
+// LoadTestResults reads the results of every version of the stats file.
+// Rows that cannot be parsed are skipped.
+func LoadTestResults() ([]game.TestResult, error) {
+	stats, err := readStatsFile(StatsFileName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]game.TestResult, 0, len(stats.records))
+	for _, record := range stats.records {
+		fields := make(map[string]string, len(stats.header))
+		for i, column := range stats.header {
+			if i < len(record) {
+				fields[column] = record[i]
+			}
 		}
 
-		results = append(results, game.TestResult{
-			Timestamp:    timestamp,
-			WPM:          wpm,
-			Accuracy:     accuracy,
-			TestDuration: duration,
-			TotalWords:   totalWords,
-			Errors:       errors,
-			TotalChars:   totalChars,
-		})
+		if result, ok := decodeResult(fields); ok {
+			results = append(results, result)
+		}
 	}
 
 	return results, nil
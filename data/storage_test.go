@@ -0,0 +1,231 @@
+package data
+
+import (
+	"encoding/csv"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"typr/game"
+)
+
+// writeStats starts a test in an empty directory with content as the stats
+// file.
+func writeStats(t *testing.T, content string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(StatsFileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readStatsLines returns the version line and the CSV records of the stats
+// file.
+func readStatsLines(t *testing.T) (string, [][]string) {
+	t.Helper()
+	content, err := os.ReadFile(StatsFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, rest, _ := strings.Cut(string(content), "\n")
+	reader := csv.NewReader(strings.NewReader(rest))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return version, records
+}
+
+func saveResult(t *testing.T) game.TestResult {
+	t.Helper()
+	result := game.TestResult{
+		Timestamp:    time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC),
+		WPM:          72.5,
+		Accuracy:     98,
+		TestDuration: 60 * time.Second,
+		TotalWords:   60,
+		Errors:       2,
+		TotalChars:   300,
+		WordList:     "english",
+		Seed:         7,
+		Punctuation:  true,
+		Filter:       "-band=200",
+	}
+	if err := SaveTestResult(result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestMigrateVersion1(t *testing.T) {
+	tests := []struct {
+		name  string
+		row   string
+		check func(t *testing.T, r game.TestResult)
+	}{
+		{
+			name: "seven columns",
+			row:  "2026-01-01T10:00:00Z,55.00,97.50,30s,25,3,130",
+			check: func(t *testing.T, r game.TestResult) {
+				if r.WordList != "" || r.Seed != 0 || r.Modifiers != nil {
+					t.Errorf("old result got columns it never had: %+v", r)
+				}
+			},
+		},
+		{
+			name: "ten columns",
+			row:  "2026-01-01T10:00:00Z,55.00,97.50,30s,25,3,130,reverse+mirror,english_200,42",
+			check: func(t *testing.T, r game.TestResult) {
+				if r.WordList != "english_200" || r.Seed != 42 || !slices.Equal(r.Modifiers, []string{"reverse", "mirror"}) {
+					t.Errorf("old result lost its columns: %+v", r)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.row + "\n"
+			writeStats(t, original)
+
+			results, err := LoadTestResults()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0].WPM != 55 || results[0].TotalChars != 130 {
+				t.Fatalf("version 1 file read as %+v", results)
+			}
+			tt.check(t, results[0])
+
+			saved := saveResult(t)
+
+			backup, err := os.ReadFile(StatsFileName + ".v1.bak")
+			if err != nil {
+				t.Fatalf("no backup of the version 1 file: %v", err)
+			}
+			if string(backup) != original {
+				t.Errorf("backup is %q, want %q", backup, original)
+			}
+
+			version, records := readStatsLines(t)
+			if version != "# typr stats v3" {
+				t.Errorf("version line is %q", version)
+			}
+			if len(records) != 3 || !slices.Equal(records[0], statsColumns) {
+				t.Fatalf("migrated file has records %q", records)
+			}
+
+			results, err = LoadTestResults()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("got %d results after migrating, want 2", len(results))
+			}
+			tt.check(t, results[0])
+			if !results[1].Timestamp.Equal(saved.Timestamp) || results[1].Filter != saved.Filter || !results[1].Punctuation {
+				t.Errorf("saved result read back as %+v", results[1])
+			}
+		})
+	}
+}
+
+func TestMigrateVersion2(t *testing.T) {
+	original := "# typr stats v2\n" +
+		strings.Join(statsColumns[:10], ",") + "\n" +
+		"2026-01-01T10:00:00Z,55.00,97.50,30s,25,3,130,randcase,english,42\n"
+	writeStats(t, original)
+
+	saveResult(t)
+
+	backup, err := os.ReadFile(StatsFileName + ".v2.bak")
+	if err != nil || string(backup) != original {
+		t.Fatalf("version 2 file was not backed up as it was: %q, %v", backup, err)
+	}
+	version, records := readStatsLines(t)
+	if version != "# typr stats v3" || len(records) != 3 || !slices.Equal(records[0], statsColumns) {
+		t.Fatalf("migrated file is %q with records %q", version, records)
+	}
+
+	results, err := LoadTestResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Seed != 42 || results[0].Punctuation || results[0].Filter != "" {
+		t.Errorf("results after migrating: %+v", results)
+	}
+}
+
+func TestVersionLineOnly(t *testing.T) {
+	for _, content := range []string{"# typr stats v2\n", "# typr stats v3"} {
+		t.Run(strings.TrimSpace(content), func(t *testing.T) {
+			writeStats(t, content)
+
+			results, err := LoadTestResults()
+			if err != nil {
+				t.Fatalf("file with only a version line: %v", err)
+			}
+			if len(results) != 0 {
+				t.Fatalf("got %d results from a file without rows", len(results))
+			}
+
+			saveResult(t)
+
+			version, records := readStatsLines(t)
+			if version != "# typr stats v3" {
+				t.Errorf("version line is %q", version)
+			}
+			if len(records) != 2 || !slices.Equal(records[0], statsColumns) {
+				t.Fatalf("file has records %q, want the header and one row", records)
+			}
+			if _, err := os.Stat(StatsFileName + ".v2.bak"); !os.IsNotExist(err) {
+				t.Error("a file without rows was backed up")
+			}
+		})
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	header := append(slices.Clone(statsColumns), "mood")
+	original := "# typr stats v9\n" +
+		strings.Join(header, ",") + "\n" +
+		"2026-01-01T10:00:00Z,55.00,97.50,30s,25,3,130,,english,42,false,true,,happy\n"
+	writeStats(t, original)
+
+	results, err := LoadTestResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Seed != 42 || !results[0].Numbers {
+		t.Fatalf("newer file read as %+v", results)
+	}
+
+	saveResult(t)
+
+	version, records := readStatsLines(t)
+	if version != "# typr stats v9" {
+		t.Errorf("newer file was rewritten with version line %q", version)
+	}
+	if len(records) != 3 || !slices.Equal(records[0], header) {
+		t.Fatalf("newer file has records %q", records)
+	}
+	if records[1][len(header)-1] != "happy" {
+		t.Errorf("unknown column lost its value: %q", records[1])
+	}
+	if len(records[2]) != len(header) || records[2][len(header)-1] != "" {
+		t.Errorf("new row does not follow the newer header: %q", records[2])
+	}
+	if matches, _ := os.ReadDir("."); len(matches) != 1 {
+		t.Errorf("newer file was migrated or backed up: %d files", len(matches))
+	}
+
+	results, err = LoadTestResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].Filter != "-band=200" {
+		t.Errorf("results after saving to a newer file: %+v", results)
+	}
+}
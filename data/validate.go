@@ -0,0 +1,227 @@
+package data
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Diagnostic is a problem with one line of a word list. Warnings are fixed
+// while loading, errors reject the line. JSON lists have no line numbers, so
+// their diagnostics name the word's position instead.
+type Diagnostic struct {
+	Line    int
+	Reason  string
+	Warning bool
+}
+
+func (d Diagnostic) String() string {
+	reason := d.Reason
+	if d.Warning {
+		reason = "warning: " + reason
+	}
+	if d.Line == 0 {
+		return reason
+	}
+	return fmt.Sprintf("line %d: %s", d.Line, reason)
+}
+
+// WordListReport is the result of checking a word list: the words that were
+// accepted, with duplicates merged, and the problems found on the way.
+type WordListReport struct {
+	Format      WordListFormat
+	Words       []Word
+	Diagnostics []Diagnostic
+
+	seen map[string]int
+}
+
+func (r *WordListReport) Errors() int {
+	n := 0
+	for _, d := range r.Diagnostics {
+		if !d.Warning {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *WordListReport) Warnings() int {
+	return len(r.Diagnostics) - r.Errors()
+}
+
+func (r *WordListReport) reject(line int, format string, args ...any) {
+	r.Diagnostics = append(r.Diagnostics, Diagnostic{Line: line, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (r *WordListReport) warn(line int, format string, args ...any) {
+	r.Diagnostics = append(r.Diagnostics, Diagnostic{Line: line, Reason: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// add accepts a word unless it is empty or contains whitespace. A duplicate
+// adds its frequency to the first occurrence when merge is set, and is
+// dropped otherwise. where describes the word's position for diagnostics.
+func (r *WordListReport) add(line int, where, word string, freq int, merge bool) {
+	switch {
+	case word == "":
+		r.reject(line, "%sempty word", where)
+		return
+	case strings.IndexFunc(word, unicode.IsSpace) >= 0:
+		r.reject(line, "%sword %q contains whitespace", where, word)
+		return
+	}
+
+	if i, ok := r.seen[word]; ok {
+		if merge {
+			r.Words[i].Frequency += freq
+			r.warn(line, "%sduplicate of %q, frequencies merged", where, word)
+		} else {
+			r.warn(line, "%sduplicate of %q, ignored", where, word)
+		}
+		return
+	}
+	if r.seen == nil {
+		r.seen = make(map[string]int)
+	}
+	r.seen[word] = len(r.Words)
+	r.Words = append(r.Words, Word{Text: word, Frequency: freq})
+}
+
+// CheckWords validates a word list in any of the supported formats, see
+// DetectFormat. Only reading r can fail, every other problem is reported.
+func CheckWords(r io.Reader) (*WordListReport, error) {
+	reader := bufio.NewReaderSize(r, detectSize)
+	format := DetectFormat(reader)
+	report := &WordListReport{Format: format}
+
+	var err error
+	switch format {
+	case FormatJSON:
+		err = report.checkJSON(reader)
+	case FormatPlain:
+		err = report.checkPlain(reader)
+	default:
+		err = report.checkCSV(reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read word list: %w", err)
+	}
+
+	// A list that no word can be drawn from fails to load as a whole
+	if !slices.ContainsFunc(report.Words, func(w Word) bool { return w.Frequency > 0 }) {
+		report.reject(0, "%v", ErrEmptyWordBank)
+	}
+	return report, nil
+}
+
+// checkCSV reads word,frequency lines. Lines that are malformed or have a
+// negative frequency are rejected, and duplicate words are merged by adding
+// up their frequencies.
+func (r *WordListReport) checkCSV(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		record, err := parseCSVLine(text)
+		if err != nil {
+			r.reject(line, "malformed CSV: %v", err)
+			continue
+		}
+		if len(record) != 2 {
+			r.reject(line, "expected word,frequency but found %d fields", len(record))
+			continue
+		}
+
+		word := record[0]
+		freq, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		switch {
+		case err != nil:
+			r.reject(line, "frequency %q of %q is not a whole number", record[1], word)
+			continue
+		case freq < 0:
+			r.reject(line, "negative frequency %d for %q", freq, word)
+			continue
+		case freq == 0:
+			r.warn(line, "%q has frequency 0 and is never drawn", word)
+		}
+
+		r.add(line, "", word, freq, true)
+	}
+	return scanner.Err()
+}
+
+// checkPlain reads one word per line. Words get Zipf frequencies by their
+// position unless a "# frequency: uniform" header asks for equal ones.
+func (r *WordListReport) checkPlain(reader io.Reader) error {
+	uniform := false
+
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if comment, ok := strings.CutPrefix(text, "#"); ok {
+			key, value, _ := strings.Cut(comment, ":")
+			if strings.EqualFold(strings.TrimSpace(key), "frequency") {
+				uniform = strings.EqualFold(strings.TrimSpace(value), "uniform")
+			}
+			continue
+		}
+
+		freq := 1
+		if !uniform {
+			freq = ZipfFrequency(len(r.Words) + 1)
+		}
+		r.add(line, "", text, freq, false)
+	}
+	return scanner.Err()
+}
+
+// checkJSON reads a monkeytype language file. Lists ordered by frequency get
+// Zipf frequencies, others are drawn uniformly like monkeytype does.
+func (r *WordListReport) checkJSON(reader io.Reader) error {
+	var list monkeytypeList
+	if err := json.NewDecoder(reader).Decode(&list); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok || err == io.ErrUnexpectedEOF {
+			r.reject(0, "malformed JSON: %v", err)
+			return nil
+		}
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			r.reject(0, "malformed monkeytype word list: %v", err)
+			return nil
+		}
+		return err
+	}
+
+	for i, word := range list.Words {
+		freq := 1
+		if list.OrderedByFrequency {
+			freq = ZipfFrequency(len(r.Words) + 1)
+		}
+		r.add(0, fmt.Sprintf("word %d: ", i+1), strings.TrimSpace(word), freq, false)
+	}
+	return nil
+}
+
+// parseCSVLine splits a single line, so that a broken quote cannot swallow
+// the lines after it.
+func parseCSVLine(line string) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.FieldsPerRecord = -1
+	record, err := reader.Read()
+	if parseErr, ok := err.(*csv.ParseError); ok {
+		// The position is always line 1, so only the cause is useful
+		return nil, parseErr.Err
+	}
+	return record, err
+}
@@ -1,11 +1,13 @@
 package data
 
 import (
-	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"os"
-	"strconv"
+	"sort"
 	"time"
 )
 
@@ -14,75 +16,124 @@ type Word struct {
 	Frequency int
 }
 
+var ErrEmptyWordBank = errors.New("word list has no words with a positive frequency")
+
 type WordBank struct {
 	Words []Word
 	Total int
+	Seed  int64
+	rng   *rand.Rand
+
+	// cumulative[i] is the sum of the frequencies of Words[0..i], which lets
+	// SelectRandomWord binary search for a weighted draw
+	cumulative []int
 }
 
-func LoadWords(filename string) (*WordBank, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open words file: %w", err)
+// NewWordBank builds a bank from words. Words without a positive frequency
+// can never be drawn and are dropped.
+func NewWordBank(words []Word) (*WordBank, error) {
+	wb := &WordBank{
+		Words:      make([]Word, 0, len(words)),
+		cumulative: make([]int, 0, len(words)),
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	for _, word := range words {
+		if word.Frequency <= 0 {
+			continue
+		}
+		wb.Total += word.Frequency
+		wb.Words = append(wb.Words, word)
+		wb.cumulative = append(wb.cumulative, wb.Total)
 	}
 
-	wb := &WordBank{
-		Words: make([]Word, 0, len(records)),
-		Total: 0,
+	if len(wb.Words) == 0 {
+		return nil, ErrEmptyWordBank
 	}
+	return wb, nil
+}
 
-	for _, record := range records {
-		if len(record) != 2 {
-			continue
-		}
+// NewSeed returns a fresh seed for word generation.
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
 
-		text := record[0]
-		freq, err := strconv.Atoi(record[1])
-		if err != nil {
-			continue
-		}
+// SetSeed resets the bank's random source, so that the same seed always
+// generates the same sequence of words.
+func (wb *WordBank) SetSeed(seed int64) {
+	wb.Seed = seed
+	wb.rng = rand.New(rand.NewSource(seed))
+}
 
-		wb.Words = append(wb.Words, Word{
-			Text:      text,
-			Frequency: freq,
-		})
-		wb.Total += freq
+// random returns the bank's random source, seeding it on first use.
+func (wb *WordBank) random() *rand.Rand {
+	if wb.rng == nil {
+		wb.SetSeed(NewSeed())
 	}
+	return wb.rng
+}
 
-	return wb, nil
+func LoadWords(filename string) (*WordBank, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open words file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadWords(file)
 }
 
-func (wb *WordBank) SelectRandomWord() string {
-	if len(wb.Words) == 0 {
-		return ""
+// ReadWords reads a word list in any supported format. Invalid lines are
+// skipped; CheckWords reports them.
+func ReadWords(r io.Reader) (*WordBank, error) {
+	report, err := CheckWords(r)
+	if err != nil {
+		return nil, err
 	}
 
-	target := rand.Intn(wb.Total)
-	current := 0
+	return NewWordBank(report.Words)
+}
 
-	for _, word := range wb.Words {
-		current += word.Frequency
-		if current > target {
-			return word.Text
-		}
+func (wb *WordBank) SelectRandomWord() string {
+	if len(wb.cumulative) == 0 || wb.Total <= 0 {
+		return ""
 	}
 
-	return wb.Words[len(wb.Words)-1].Text
+	target := wb.random().Intn(wb.Total)
+	idx := sort.Search(len(wb.cumulative), func(i int) bool {
+		return wb.cumulative[i] > target
+	})
+
+	return wb.Words[idx].Text
 }
 
-func (wb *WordBank) GenerateSequence(count int) []string {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
+func (wb *WordBank) GenerateSequence(count int, options GenerateOptions) []string {
+	generator := wb.NewSentenceGenerator(options)
 	sequence := make([]string, count)
 
 	for i := range count {
-		sequence[i] = wb.SelectRandomWord()
+		sequence[i], _ = generator.Next()
 	}
 
 	return sequence
 }
+
+// Next implements game.WordSource with an endless stream of random words.
+func (wb *WordBank) Next() (string, bool) {
+	if len(wb.cumulative) == 0 {
+		return "", false
+	}
+	return wb.SelectRandomWord(), true
+}
+
+// Adaptive returns a bank biased towards words with the user's weakest
+// characters and bigrams. Frequencies are flattened first, so that the bias
+// is not drowned out by the most common words.
+func (wb *WordBank) Adaptive(analytics *Analytics) (*WordBank, error) {
+	overall := analytics.overall()
+	words := make([]Word, len(wb.Words))
+	for i, word := range wb.Words {
+		weight := math.Sqrt(float64(word.Frequency)) * 100 * analytics.wordWeight(word.Text, &overall)
+		words[i] = Word{Text: word.Text, Frequency: max(1, int(weight))}
+	}
+	return NewWordBank(words)
+}
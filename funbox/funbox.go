@@ -0,0 +1,161 @@
+package funbox
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+	"typr/game"
+)
+
+// Modifier is a funbox option that changes the generated text, the way it is
+// displayed, or how the game treats input. Each modifier implements Modifier
+// and at least one of WordModifier, LineModifier or GameModifier.
+type Modifier interface {
+	Name() string
+}
+
+// WordModifier rewrites every generated word before it reaches the game.
+type WordModifier interface {
+	Modifier
+	ModifyWord(word string) string
+}
+
+// LineModifier changes a rendered line of text before it is drawn.
+type LineModifier interface {
+	Modifier
+	ModifyLine(line []Cell, frame Frame) []Cell
+}
+
+// GameModifier adjusts the game rules before the test starts.
+type GameModifier interface {
+	Modifier
+	Configure(g *game.GameState)
+}
+
+type CellState int
+
+const (
+	CellUntyped CellState = iota
+	CellCorrect
+	CellIncorrect
+	CellCursor
+	CellHidden
+	CellTyped // typed text that does not reveal whether it was correct
+)
+
+// Cell is a single displayed character and the index of the word it
+// belongs to.
+type Cell struct {
+	Char  rune
+	Word  int
+	State CellState
+}
+
+// Frame describes the game at the moment a line is rendered.
+type Frame struct {
+	CurrentWord int
+	Elapsed     time.Duration
+}
+
+// registry creates modifiers by name. Random modifiers are seeded so that a
+// test can be reproduced from its seed.
+var registry = map[string]func(seed int64) Modifier{
+	"reverse":   func(int64) Modifier { return Reverse{} },
+	"mirror":    func(int64) Modifier { return Mirror{} },
+	"randcase":  func(seed int64) Modifier { return NewRandomCase(seed) },
+	"nospace":   func(int64) Modifier { return NoSpace{} },
+	"readahead": func(int64) Modifier { return ReadAhead{} },
+	"memory":    func(int64) Modifier { return Memory{Visible: 5 * time.Second} },
+	"58008":     func(seed int64) Modifier { return NewNumbers(seed) },
+}
+
+// Names returns the names of all available modifiers in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func New(name string, seed int64) (Modifier, error) {
+	newModifier, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown funbox modifier: %s", name)
+	}
+	return newModifier(seed), nil
+}
+
+// NewAll creates a fresh modifier for each name. Every modifier gets a seed
+// of its own, derived from seed and its name, so that the modifiers and word
+// generation do not make the same random choices.
+func NewAll(names []string, seed int64) ([]Modifier, error) {
+	mods := make([]Modifier, 0, len(names))
+	for _, name := range names {
+		mod, err := New(name, modifierSeed(seed, name))
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, mod)
+	}
+	return mods, nil
+}
+
+func modifierSeed(seed int64, name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return seed ^ int64(hash.Sum64())
+}
+
+type modifiedSource struct {
+	source game.WordSource
+	mods   []WordModifier
+}
+
+func (s *modifiedSource) Next() (string, bool) {
+	word, ok := s.source.Next()
+	if !ok {
+		return "", false
+	}
+	for _, mod := range s.mods {
+		word = mod.ModifyWord(word)
+	}
+	return word, true
+}
+
+// WrapSource passes every word from source through the word modifiers.
+func WrapSource(source game.WordSource, mods []Modifier) game.WordSource {
+	wrapped := &modifiedSource{source: source}
+	for _, mod := range mods {
+		if wm, ok := mod.(WordModifier); ok {
+			wrapped.mods = append(wrapped.mods, wm)
+		}
+	}
+	if len(wrapped.mods) == 0 {
+		return source
+	}
+	return wrapped
+}
+
+// Configure applies the game modifiers to g and records the names of all
+// active modifiers so that they end up in the test result.
+func Configure(g *game.GameState, mods []Modifier) {
+	for _, mod := range mods {
+		if gm, ok := mod.(GameModifier); ok {
+			gm.Configure(g)
+		}
+		g.Modifiers = append(g.Modifiers, mod.Name())
+	}
+}
+
+// ModifyLine runs a rendered line through every line modifier.
+func ModifyLine(line []Cell, frame Frame, mods []Modifier) []Cell {
+	for _, mod := range mods {
+		if lm, ok := mod.(LineModifier); ok {
+			line = lm.ModifyLine(line, frame)
+		}
+	}
+	return line
+}
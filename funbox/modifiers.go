@@ -0,0 +1,121 @@
+package funbox
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+	"typr/game"
+	"unicode"
+)
+
+// Reverse spells every word backwards.
+type Reverse struct{}
+
+func (Reverse) Name() string { return "reverse" }
+
+func (Reverse) ModifyWord(word string) string {
+	runes := []rune(word)
+	slices.Reverse(runes)
+	return string(runes)
+}
+
+// Mirror displays every line right to left.
+type Mirror struct{}
+
+func (Mirror) Name() string { return "mirror" }
+
+func (Mirror) ModifyLine(line []Cell, frame Frame) []Cell {
+	mirrored := slices.Clone(line)
+	slices.Reverse(mirrored)
+	return mirrored
+}
+
+// RandomCase randomly capitalizes letters.
+type RandomCase struct {
+	rng *rand.Rand
+}
+
+func NewRandomCase(seed int64) *RandomCase {
+	return &RandomCase{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (*RandomCase) Name() string { return "randcase" }
+
+func (r *RandomCase) ModifyWord(word string) string {
+	var b strings.Builder
+	for _, char := range word {
+		if r.rng.Intn(2) == 0 {
+			char = unicode.ToUpper(char)
+		}
+		b.WriteRune(char)
+	}
+	return b.String()
+}
+
+// NoSpace runs the words together so that no spaces have to be typed.
+type NoSpace struct{}
+
+func (NoSpace) Name() string { return "nospace" }
+
+func (NoSpace) Configure(g *game.GameState) {
+	g.NoSpace = true
+}
+
+// ReadAhead hides the word that is currently being typed.
+type ReadAhead struct{}
+
+func (ReadAhead) Name() string { return "readahead" }
+
+func (ReadAhead) ModifyLine(line []Cell, frame Frame) []Cell {
+	for i := range line {
+		if line[i].Word != frame.CurrentWord {
+			continue
+		}
+		if line[i].State == CellCursor {
+			line[i].Char = ' '
+		} else {
+			line[i].State = CellHidden
+		}
+	}
+	return line
+}
+
+// Memory shows the text for a few seconds after the test starts and hides
+// everything that has not been typed yet afterwards.
+type Memory struct {
+	Visible time.Duration
+}
+
+func (Memory) Name() string { return "memory" }
+
+func (m Memory) ModifyLine(line []Cell, frame Frame) []Cell {
+	if frame.Elapsed < m.Visible {
+		return line
+	}
+	for i := range line {
+		if line[i].State == CellUntyped {
+			line[i].State = CellHidden
+		}
+	}
+	return line
+}
+
+// Numbers replaces every word with random digits of the same length.
+type Numbers struct {
+	rng *rand.Rand
+}
+
+func NewNumbers(seed int64) *Numbers {
+	return &Numbers{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (*Numbers) Name() string { return "58008" }
+
+func (n *Numbers) ModifyWord(word string) string {
+	digits := make([]byte, len([]rune(word)))
+	for i := range digits {
+		digits[i] = byte('0' + n.rng.Intn(10))
+	}
+	return string(digits)
+}
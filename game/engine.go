@@ -1,20 +1,43 @@
 package game
 
 import (
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+const DefaultTabWidth = 4
+
 type GameStatus int
 
 const (
 	StatusMenu GameStatus = iota
+	StatusWaiting
 	StatusTyping
 	StatusFinished
 	StatusPaused
 )
 
+// WordSource supplies words to a game on demand. Next reports false once the
+// source has no more words to give.
+type WordSource interface {
+	Next() (string, bool)
+}
+
+// Keystroke is a single character typed during a test. Expected is zero for
+// characters typed past the end of a word, and Previous is the expected
+// character before this one, which together form a bigram.
+type Keystroke struct {
+	Expected rune
+	Typed    rune
+	Previous rune
+	Latency  time.Duration
+}
+
 type GameState struct {
 	Words          []string
+	Source         WordSource
+	History        []string
 	CurrentWordIdx int
 	CurrentCharIdx int
 	UserInput      string
@@ -25,6 +48,20 @@ type GameState struct {
 	CorrectChars   int
 	Status         GameStatus
 	Finished       bool
+	CodeMode       bool
+	AutoIndent     bool
+	TabWidth       int
+	NoSpace        bool
+	Modifiers      []string
+	WordList       string
+	Lesson         bool // words are generated for a touch typing lesson
+	Seed           int64
+	Punctuation    bool
+	Numbers        bool
+	Filter         string // flags of the word filter the words were drawn with
+	Keystrokes     []Keystroke
+	lastKeystroke  time.Time
+	lastExpected   rune
 }
 
 type TestResult struct {
@@ -35,6 +72,12 @@ type TestResult struct {
 	TotalWords   int
 	Errors       int
 	TotalChars   int
+	Modifiers    []string
+	WordList     string
+	Seed         int64
+	Punctuation  bool
+	Numbers      bool
+	Filter       string
 }
 
 func NewGame(words []string, duration time.Duration) *GameState {
@@ -49,7 +92,48 @@ func NewGame(words []string, duration time.Duration) *GameState {
 		CorrectChars:   0,
 		Status:         StatusMenu,
 		Finished:       false,
+		TabWidth:       DefaultTabWidth,
+	}
+}
+
+// NewStreamGame creates a game that pulls its words lazily from source, so a
+// timed test never runs out of text.
+func NewStreamGame(source WordSource, duration time.Duration) *GameState {
+	g := NewGame(nil, duration)
+	g.Source = source
+	return g
+}
+
+// NewCodeGame creates a game over lines of source code. Each line is typed as
+// a single unit and Enter advances to the next one. Tabs are expanded to
+// spaces so that the Tab key can be matched against indentation.
+func NewCodeGame(lines []string, duration time.Duration, autoIndent bool) *GameState {
+	expanded := make([]string, len(lines))
+	for i, line := range lines {
+		expanded[i] = expandTabs(strings.TrimRight(line, " \t\r"), DefaultTabWidth)
+	}
+
+	g := NewGame(expanded, duration)
+	g.CodeMode = true
+	g.AutoIndent = autoIndent
+	g.skipIndent()
+	return g
+}
+
+func expandTabs(line string, width int) string {
+	var b strings.Builder
+	col := 0
+	for _, char := range line {
+		if char == '\t' {
+			n := width - col%width
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(char)
+		col++
 	}
+	return b.String()
 }
 
 func (g *GameState) Start() {
@@ -57,7 +141,16 @@ func (g *GameState) Start() {
 	g.Status = StatusTyping
 }
 
+// WaitForInput arms the game so that the timer starts on the first keystroke.
+func (g *GameState) WaitForInput() {
+	g.Status = StatusWaiting
+}
+
 func (g *GameState) ProcessChar(char rune) {
+	if g.Status == StatusWaiting {
+		g.Start()
+	}
+
 	if g.Status != StatusTyping {
 		return
 	}
@@ -67,20 +160,50 @@ func (g *GameState) ProcessChar(char rune) {
 		return
 	}
 
-	currentWord := g.GetCurrentWord()
-	if currentWord == "" {
+	if !g.HasWord(g.CurrentWordIdx) {
 		return
 	}
 
-	switch char {
-	case ' ':
+	switch {
+	case g.CodeMode && (char == '\n' || char == '\r'):
+		g.processNewline()
+	case g.CodeMode && char == '\t':
+		g.processTab()
+	case !g.CodeMode && !g.NoSpace && char == ' ':
 		g.processSpace()
 	default:
 		g.processTypedChar(char)
 	}
+
+	// Words advance on their own when there is no separator to type, which
+	// includes the last word of a text, so that the test ends with it
+	lastWord := !g.HasWord(g.CurrentWordIdx + 1)
+	if (g.NoSpace || lastWord) && g.CurrentCharIdx >= utf8.RuneCountInString(g.GetCurrentWord()) {
+		g.nextWord()
+	}
+}
+
+// recordKeystroke appends a keystroke with the time since the previous one.
+func (g *GameState) recordKeystroke(expected, typed rune) {
+	now := time.Now()
+	var latency time.Duration
+	if !g.lastKeystroke.IsZero() {
+		latency = now.Sub(g.lastKeystroke)
+	}
+
+	g.Keystrokes = append(g.Keystrokes, Keystroke{
+		Expected: expected,
+		Typed:    typed,
+		Previous: g.lastExpected,
+		Latency:  latency,
+	})
+	g.lastKeystroke = now
+	g.lastExpected = expected
 }
 
 func (g *GameState) processSpace() {
+	g.recordKeystroke(' ', ' ')
+
 	// Space character counting
 	g.TotalChars++
 	g.CorrectChars++ // Space is always correct if we reach this point
@@ -88,11 +211,59 @@ func (g *GameState) processSpace() {
 	g.nextWord()
 }
 
+func (g *GameState) processNewline() {
+	g.recordKeystroke('\n', '\n')
+
+	// Newlines are counted like spaces between words
+	g.TotalChars++
+	g.CorrectChars++
+
+	g.nextWord()
+	g.skipIndent()
+}
+
+// processTab fills the expected indentation up to the next tab stop. A tab
+// typed where no whitespace is expected counts as a single wrong character.
+func (g *GameState) processTab() {
+	currentWord := []rune(g.GetCurrentWord())
+	width := g.TabWidth
+	if width <= 0 {
+		width = DefaultTabWidth
+	}
+
+	n := 0
+	for idx := g.CurrentCharIdx; idx < len(currentWord) && currentWord[idx] == ' ' && n < width-g.CurrentCharIdx%width; idx++ {
+		n++
+	}
+	if n == 0 {
+		n = 1
+	}
+
+	for range n {
+		g.processTypedChar(' ')
+	}
+}
+
+// skipIndent consumes the leading whitespace of the current line without
+// counting it towards the stats.
+func (g *GameState) skipIndent() {
+	if !g.CodeMode || !g.AutoIndent || g.CurrentCharIdx != 0 {
+		return
+	}
+
+	currentWord := []rune(g.GetCurrentWord())
+	for g.CurrentCharIdx < len(currentWord) && currentWord[g.CurrentCharIdx] == ' ' {
+		g.UserInput += " "
+		g.CurrentCharIdx++
+	}
+}
+
 func (g *GameState) processTypedChar(char rune) {
-	currentWord := g.GetCurrentWord()
+	currentWord := []rune(g.GetCurrentWord())
 
 	if g.CurrentCharIdx < len(currentWord) {
-		expectedChar := rune(currentWord[g.CurrentCharIdx])
+		expectedChar := currentWord[g.CurrentCharIdx]
+		g.recordKeystroke(expectedChar, char)
 
 		g.UserInput += string(char)
 		g.CurrentCharIdx++
@@ -104,6 +275,7 @@ func (g *GameState) processTypedChar(char rune) {
 			g.Errors++
 		}
 	} else {
+		g.recordKeystroke(0, char)
 		g.UserInput += string(char)
 		g.TotalChars++
 		g.Errors++
@@ -111,23 +283,58 @@ func (g *GameState) processTypedChar(char rune) {
 }
 
 func (g *GameState) nextWord() {
+	g.History = append(g.History, g.UserInput)
 	g.CurrentWordIdx++
 	g.CurrentCharIdx = 0
 	g.UserInput = ""
 
-	if g.CurrentWordIdx >= len(g.Words) {
+	if !g.HasWord(g.CurrentWordIdx) {
 		g.Finish()
 	}
 }
 
-func (g *GameState) GetCurrentWord() string {
-	if g.CurrentWordIdx >= len(g.Words) {
+// HasWord reports whether the word at idx exists, pulling more words from
+// the source if needed.
+func (g *GameState) HasWord(idx int) bool {
+	for idx >= len(g.Words) && g.Source != nil {
+		word, ok := g.Source.Next()
+		if !ok {
+			g.Source = nil
+			break
+		}
+		g.Words = append(g.Words, word)
+	}
+	return idx < len(g.Words)
+}
+
+func (g *GameState) WordAt(idx int) string {
+	if !g.HasWord(idx) {
 		return ""
 	}
-	return g.Words[g.CurrentWordIdx]
+	return g.Words[idx]
+}
+
+// InputAt returns what the user typed for the word at idx so far.
+func (g *GameState) InputAt(idx int) string {
+	if idx < len(g.History) {
+		return g.History[idx]
+	}
+	if idx == g.CurrentWordIdx {
+		return g.UserInput
+	}
+	return ""
 }
 
+func (g *GameState) GetCurrentWord() string {
+	return g.WordAt(g.CurrentWordIdx)
+}
+
+// IsTimeUp reports whether a timed test has run out. Tests without a
+// duration only end when the text runs out.
 func (g *GameState) IsTimeUp() bool {
+	if g.StartTime.IsZero() || g.TestDuration <= 0 {
+		return false
+	}
 	return time.Since(g.StartTime) >= g.TestDuration
 }
 
@@ -174,10 +381,24 @@ func (g *GameState) GetTestResult() TestResult {
 		TotalWords:   g.CurrentWordIdx,
 		Errors:       g.Errors,
 		TotalChars:   g.TotalChars,
+		Modifiers:    g.Modifiers,
+		WordList:     g.WordList,
+		Seed:         g.Seed,
+		Punctuation:  g.Punctuation,
+		Numbers:      g.Numbers,
+		Filter:       g.Filter,
 	}
 }
 
 func (g *GameState) GetProgress() float64 {
+	// Streams have no end, so progress follows the clock
+	if g.Source != nil {
+		if g.TestDuration <= 0 {
+			return 0
+		}
+		return min(g.GetElapsedTime().Seconds()/g.TestDuration.Seconds()*100.0, 100.0)
+	}
+
 	if len(g.Words) == 0 {
 		return 0
 	}
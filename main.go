@@ -1,37 +1,160 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 	"typr/data"
+	"typr/funbox"
 	"typr/game"
 	"typr/ui"
 )
 
 func main() {
-	wordBank, err := data.LoadWords("words.txt")
+	wordsFlag := flag.String("words", "", "word list ID or path to a word list file")
+	seedFlag := flag.Int64("seed", 0, "seed of an earlier test to generate the same text again")
+	corpusFlag := flag.String("corpus", "", "text file to train the markov text source on")
+	textFlag := flag.String("text", "", "type the text of a file, or of stdin with -")
+	asciiFlag := flag.Bool("ascii", true, "convert typographic quotes and dashes in custom text to ASCII")
+	chunkFlag := flag.Int("chunk", data.DefaultChunkWords, "approximate number of words per custom text test")
+	bookFlag := flag.String("book", "", "ID of an imported book to type")
+	codeDirFlag := flag.String("code-dir", "", "directory with source files to take code test snippets from")
+	extFlag := flag.String("ext", ".go,.py,.ts,.js,.rs", "comma separated file extensions for -code-dir")
+	minLenFlag := flag.Int("min-len", 0, "only use words with at least this many characters")
+	maxLenFlag := flag.Int("max-len", 0, "only use words with at most this many characters")
+	allowedFlag := flag.String("allowed", "", "only use words made of these characters, or home-row, left-hand or right-hand")
+	requireFlag := flag.String("require", "", "only use words containing all of these characters")
+	excludeFlag := flag.String("exclude", "", "skip words containing any of these characters")
+	layoutFlag := flag.String("layout", "", "keyboard layout to emulate on a QWERTY system, by name or file")
+	bandFlag := flag.String("band", "", "frequency band of words to use, like 200, 1k or 1k-5k")
+	flag.Parse()
+
+	if runCommand(flag.Args()) {
+		return
+	}
+
+	config, err := data.LoadConfig()
 	if err != nil {
-		log.Fatalf("Error loading words: %v", err)
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	registry, err := data.DiscoverWordLists()
+	if err != nil {
+		log.Fatalf("Error loading word lists: %v", err)
 	}
 
 	setupSignalHandling()
 
+	if len(registry.Skipped) > 0 {
+		var message strings.Builder
+		message.WriteString("These word lists could not be read and were skipped. Run typr wordlist check for details.\n")
+		for _, skipped := range registry.Skipped {
+			fmt.Fprintf(&message, "\n%s: %v", skipped.Path, skipped.Err)
+		}
+		ui.ShowMessage(" Word Lists Skipped ", message.String())
+	}
+
+	options := ui.DefaultTestOptions()
+	options.WordList, err = registry.ResolveDefault(*wordsFlag, config)
+	if err != nil {
+		log.Fatalf("Error loading words: %v", err)
+	}
+	options.Seed = *seedFlag
+	if *layoutFlag != "" {
+		if _, err := data.LoadLayout(*layoutFlag); err != nil {
+			log.Fatalf("Error loading layout: %v", err)
+		}
+		options.Layout = *layoutFlag
+	}
+	options.Filter = data.WordFilter{
+		MinLength: *minLenFlag,
+		MaxLength: *maxLenFlag,
+		Allowed:   data.ResolveCharSet(*allowedFlag),
+		Required:  *requireFlag,
+		Excluded:  *excludeFlag,
+	}
+	options.Filter.MinRank, options.Filter.MaxRank, err = data.ParseRankBand(*bandFlag)
+	if err != nil {
+		log.Fatalf("Error in -band: %v", err)
+	}
+	if !options.Filter.IsZero() {
+		checkWordFilter(registry, options)
+	}
+	options.Corpus = config.Corpus
+	options.CodeDir = *codeDirFlag
+	options.CodeExts = data.ParseExtensions(*extFlag)
+	if *corpusFlag != "" {
+		options.Corpus = *corpusFlag
+		options.Source = ui.SourceMarkov
+	}
+
+	if *textFlag != "" {
+		chunks, err := data.LoadCustomText(*textFlag, data.TextOptions{ASCII: *asciiFlag, ChunkWords: *chunkFlag})
+		if err != nil {
+			log.Fatalf("Error loading text: %v", err)
+		}
+		runCustomTextTest(chunks, options)
+	} else if *bookFlag != "" {
+		book, err := data.LoadBook(*bookFlag)
+		if err != nil {
+			log.Fatalf("Error loading book: %v", err)
+		}
+		runBookTest(book, options, *chunkFlag)
+	} else {
+		offerBookResume(options, *chunkFlag)
+	}
+
 	for {
 		choice := ui.ShowMainMenu()
 
 		switch choice {
 		case ui.StartTest:
-			runTypingTest(wordBank)
+			runTypingTest(registry, options)
+		case ui.StartCodeTest:
+			runCodeTest(options)
+		case ui.ReviewTest:
+			runReviewTest(registry, options)
+		case ui.LessonTest:
+			runLessonTest(registry, options)
 		case ui.ViewStats:
-			showStats()
+			showStats(options)
+		case ui.Settings:
+			ui.ShowSettingsMenu(&options, registry.Lists)
 		case ui.Exit:
 			fmt.Println("Thanks for using Typr!")
 			return
 		}
+
+		// A seed from the command line only applies to the first test
+		options.Seed = 0
+	}
+}
+
+// offerBookResume asks whether to continue the most recently typed book that
+// has not been finished yet.
+func offerBookResume(options ui.TestOptions, chunkWords int) {
+	books, err := data.LoadBooks()
+	if err != nil {
+		return
+	}
+
+	for _, book := range books {
+		if book.Finished() || book.Progress.Updated.IsZero() {
+			continue
+		}
+
+		message := fmt.Sprintf("Continue typing %s at %s (%.1f%% done)?",
+			book.Title, book.CurrentSection().Title, book.Completion())
+		if ui.ShowConfirm(" Resume Book ", message) {
+			runBookTest(book, options, chunkWords)
+		}
+		return
 	}
 }
 
@@ -46,26 +169,378 @@ func setupSignalHandling() {
 	}()
 }
 
-func runTypingTest(wordBank *data.WordBank) {
+// wordBanks caches the word lists that have been loaded so far.
+var wordBanks = map[string]*data.WordBank{}
+
+func loadWordBank(registry *data.Registry, id string) (*data.WordBank, error) {
+	if wordBank, ok := wordBanks[id]; ok {
+		return wordBank, nil
+	}
+
+	list, ok := registry.Find(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown word list: %s", id)
+	}
+
+	wordBank, err := list.Load()
+	if err != nil {
+		return nil, err
+	}
+	wordBanks[id] = wordBank
+	return wordBank, nil
+}
+
+// checkWordFilter reports how many words the filter leaves, and exits if
+// none are left.
+func checkWordFilter(registry *data.Registry, options ui.TestOptions) {
+	wordBank, err := loadWordBank(registry, options.WordList)
+	if err != nil {
+		log.Fatalf("Error loading words: %v", err)
+	}
+	filtered, err := wordBank.Filter(options.Filter)
+	if err != nil {
+		log.Fatalf("Error filtering words: %v", err)
+	}
+	ui.ShowMessage(" Word Filter ", fmt.Sprintf("The filter %s matches %d of %d words.", options.Filter, len(filtered.Words), len(wordBank.Words)))
+}
+
+// showError reports an error in a dialog. Anything printed while the menus
+// are open is drawn over straight away.
+func showError(format string, args ...any) {
+	ui.ShowMessage(" Error ", fmt.Sprintf(format, args...))
+}
+
+func runTypingTest(registry *data.Registry, options ui.TestOptions) {
+	newSource, sourceID, err := wordSource(registry, options)
+	if err != nil {
+		showError("Error loading words: %v", err)
+		return
+	}
+
+	dueWords := dueWordsBySeed()
+	runTests(options, nil, func(seed int64) *game.GameState {
+		// Unknown names cannot be selected from the settings menu
+		mods, _ := funbox.NewAll(options.Modifiers, seed)
+		source := newSource(seed)
+		if options.Review {
+			source = data.MixReview(source, dueWords(seed))
+		}
+		gameState := game.NewStreamGame(funbox.WrapSource(source, mods), 60*time.Second)
+		funbox.Configure(gameState, mods)
+		gameState.WordList = sourceID
+		if options.Source != ui.SourceMarkov {
+			// The markov source ignores the generation options and filter
+			gameState.Punctuation = options.Generate.Punctuation
+			gameState.Numbers = options.Generate.Numbers
+			gameState.Filter = options.Filter.Flags()
+		}
+		return gameState
+	})
+}
+
+// markovModels caches the models trained so far by corpus path.
+var markovModels = map[string]*data.MarkovModel{}
+
+// wordSource returns a constructor for the selected text source and the ID
+// under which results are recorded.
+func wordSource(registry *data.Registry, options ui.TestOptions) (func(seed int64) game.WordSource, string, error) {
+	if options.Source == ui.SourceMarkov {
+		if options.Corpus == "" {
+			return nil, "", fmt.Errorf("no corpus configured, use -corpus or the corpus config key")
+		}
+
+		model, ok := markovModels[options.Corpus]
+		if !ok {
+			var err error
+			model, err = data.LoadMarkov(options.Corpus, data.DefaultMarkovOrder)
+			if err != nil {
+				return nil, "", err
+			}
+			markovModels[options.Corpus] = model
+		}
+
+		newSource := func(seed int64) game.WordSource {
+			return model.NewGenerator(seed)
+		}
+		return newSource, "markov:" + filepath.Base(options.Corpus), nil
+	}
+
+	wordBank, err := loadWordBank(registry, options.WordList)
+	if err != nil {
+		return nil, "", err
+	}
+	if !options.Filter.IsZero() {
+		wordBank, err = wordBank.Filter(options.Filter)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if !options.Adaptive {
+		newSource := func(seed int64) game.WordSource {
+			wordBank.SetSeed(seed)
+			return wordBank.NewSentenceGenerator(options.Generate)
+		}
+		return newSource, options.WordList, nil
+	}
+
+	// Weights follow the analytics, which change after every test, so the
+	// bank is built once per seed for a retry to get the same text
+	var adaptiveSeed int64
+	var adaptiveBank *data.WordBank
+	newSource := func(seed int64) game.WordSource {
+		if adaptiveBank == nil || seed != adaptiveSeed {
+			adaptiveBank, adaptiveSeed = wordBank, seed
+			if adaptive, err := wordBank.Adaptive(loadAnalytics(options.Layout)); err == nil {
+				adaptiveBank = adaptive
+			}
+		}
+		adaptiveBank.SetSeed(seed)
+		return adaptiveBank.NewSentenceGenerator(options.Generate)
+	}
+	return newSource, "adaptive:" + options.WordList, nil
+}
+
+// analytics caches the keystroke analytics of each layout once they have
+// been loaded.
+var analytics = map[string]*data.Analytics{}
+
+func loadAnalytics(layout string) *data.Analytics {
+	// Different IDs of the same layout share their analytics file
+	layout = data.LayoutName(layout)
+	if analytics[layout] == nil {
+		loaded, err := data.LoadAnalytics(layout)
+		if err != nil {
+			fmt.Printf("Error loading analytics: %v\n", err)
+			loaded = data.NewAnalytics(layout)
+		}
+		analytics[layout] = loaded
+	}
+	return analytics[layout]
+}
+
+func recordAnalytics(gameState *game.GameState, layout string) {
+	a := loadAnalytics(layout)
+	a.Record(gameState.Keystrokes)
+	if err := a.Save(); err != nil {
+		fmt.Printf("Error saving analytics: %v\n", err)
+	}
+}
+
+// reviewQueue caches the spaced repetition queue once it has been loaded.
+var reviewQueue *data.ReviewQueue
+
+func loadReviewQueue() *data.ReviewQueue {
+	if reviewQueue == nil {
+		loaded, err := data.LoadReviewQueue()
+		if err != nil {
+			fmt.Printf("Error loading review queue: %v\n", err)
+			loaded = data.NewReviewQueue()
+		}
+		reviewQueue = loaded
+	}
+	return reviewQueue
+}
+
+func recordReview(gameState *game.GameState) {
+	queue := loadReviewQueue()
+	queue.RecordTest(gameState, time.Now())
+	if err := queue.Save(); err != nil {
+		fmt.Printf("Error saving review queue: %v\n", err)
+	}
+}
+
+// dueWordsBySeed returns a function giving the words due for review in the
+// order of a seed. Each test changes the queue, so the words are kept for a
+// retry of the same seed.
+func dueWordsBySeed() func(seed int64) []string {
+	var lastSeed int64
+	var words []string
+	return func(seed int64) []string {
+		if seed != lastSeed {
+			lastSeed = seed
+			words = loadReviewQueue().ReviewWords(time.Now(), seed)
+		}
+		return words
+	}
+}
+
+// runReviewTest types the words that are due for review, without a time
+// limit. With nothing due it offers a regular test instead.
+func runReviewTest(registry *data.Registry, options ui.TestOptions) {
+	if len(loadReviewQueue().Due(time.Now())) == 0 {
+		if ui.ShowConfirm(" Nothing to Review ", "No mistyped words are due for review. Start a regular test instead?") {
+			runTypingTest(registry, options)
+		}
+		return
+	}
+
+	dueWords := dueWordsBySeed()
+	runTests(options, nil, func(seed int64) *game.GameState {
+		words := dueWords(seed)
+		if len(words) == 0 {
+			ui.ShowMessage(" Nothing to Review ", "All words due for review have been typed.")
+			return nil
+		}
+		gameState := game.NewGame(words, 0)
+		gameState.WordList = "review"
+		return gameState
+	})
+}
+
+// runLessonTest types pseudo-words made of the letters unlocked so far,
+// unlocking the next letter once every unlocked one reaches the targets.
+func runLessonTest(registry *data.Registry, options ui.TestOptions) {
+	wordBank, err := loadWordBank(registry, options.WordList)
+	if err != nil {
+		showError("Error loading words: %v", err)
+		return
+	}
+	lesson, err := data.LoadLesson(options.WordList, options.Layout, wordBank)
+	if err != nil {
+		showError("Error loading lesson: %v", err)
+		return
+	}
+	model := data.TrainLetterModel(wordBank)
+
+	ui.ShowMessage(" Learn Touch Typing ", fmt.Sprintf(
+		"Letters: %s   Focus: %c\nReach %d WPM at %.0f%% accuracy on every letter to unlock the next one.",
+		lesson.UnlockedLetters(), lesson.Focus(), data.LessonTargetWPM, data.LessonTargetAccuracy*100))
+
+	var current *game.GameState
+	afterTest := func(result game.TestResult) {
+		letter := lesson.Record(current.Keystrokes)
+		if err := lesson.Save(); err != nil {
+			fmt.Printf("Error saving lesson: %v\n", err)
+		}
+		if letter != 0 {
+			ui.ShowMessage(" New letter unlocked ", fmt.Sprintf("You unlocked %c. Letters: %s", letter, lesson.UnlockedLetters()))
+		}
+	}
+
+	runTests(options, afterTest, func(seed int64) *game.GameState {
+		current = game.NewGame(lesson.Words(model, seed), 0)
+		current.WordList = "lesson:" + lesson.UnlockedLetters()
+		current.Lesson = true
+		return current
+	})
+}
+
+// codeSnippets caches the snippets collected from -code-dir.
+var codeSnippets []data.Snippet
+
+func runCodeTest(options ui.TestOptions) {
+	if options.CodeDir != "" && codeSnippets == nil {
+		snippets, err := data.CollectSnippets(options.CodeDir, options.CodeExts)
+		if err != nil {
+			showError("Error loading code: %v", err)
+			return
+		}
+		codeSnippets = snippets
+	}
+
+	runTests(options, nil, func(seed int64) *game.GameState {
+		if codeSnippets == nil {
+			return game.NewCodeGame(data.RandomSnippet(seed), 120*time.Second, options.AutoIndent)
+		}
+
+		snippet := data.PickSnippet(codeSnippets, seed)
+		gameState := game.NewCodeGame(snippet.Lines, 120*time.Second, options.AutoIndent)
+		gameState.WordList = "code:" + snippet.String()
+		return gameState
+	})
+}
+
+// runCustomTextTest types through the chunks of a custom text in order,
+// without a time limit.
+func runCustomTextTest(chunks [][]string, options ui.TestOptions) {
+	chunk := -1
+	lastSeed := int64(0)
+
+	runTests(options, nil, func(seed int64) *game.GameState {
+		// Retrying keeps the seed, so a new seed means the next chunk
+		if seed != lastSeed {
+			chunk = (chunk + 1) % len(chunks)
+			lastSeed = seed
+		}
+		gameState := game.NewGame(chunks[chunk], 0)
+		gameState.WordList = "custom"
+		return gameState
+	})
+}
+
+// runBookTest types through a book from its saved position, saving the
+// progress after every test.
+func runBookTest(book *data.Book, options ui.TestOptions, chunkWords int) {
+	var chunk []string
+	lastSeed := int64(0)
+	retrying := false
+
+	afterTest := func(result game.TestResult) {
+		// A retried chunk has already been counted
+		if retrying {
+			return
+		}
+		book.Advance(result.TotalWords, result.WPM, result.Accuracy)
+		if err := book.Save(); err != nil {
+			fmt.Printf("Error saving book progress: %v\n", err)
+		}
+	}
+
+	runTests(options, afterTest, func(seed int64) *game.GameState {
+		retrying = seed == lastSeed
+		if !retrying {
+			chunk = book.NextChunk(chunkWords)
+			lastSeed = seed
+		}
+		if chunk == nil {
+			return nil
+		}
+
+		gameState := game.NewGame(chunk, 0)
+		gameState.WordList = "book:" + book.ID
+		return gameState
+	})
+}
+
+// runTests runs tests until the user returns to the main menu or newGame
+// returns nil. Every test gets a new seed unless the user retries the
+// previous one. afterTest, if set, receives each saved result.
+func runTests(options ui.TestOptions, afterTest func(game.TestResult), newGame func(seed int64) *game.GameState) {
+	seed := options.Seed
 	for {
-		words := wordBank.GenerateSequence(300)
-		duration := 60 * time.Second
+		if seed == 0 {
+			seed = data.NewSeed()
+		}
 
-		gameState := game.NewGame(words, duration)
-		tuiTest := ui.NewTUITest()
+		gameState := newGame(seed)
+		if gameState == nil {
+			return
+		}
+		gameState.Seed = seed
+		tuiTest := ui.NewTUITest(options)
 
 		tuiTest.RunTypingTest(gameState)
-		showTestResults(gameState)
+		result := showTestResults(gameState)
+		recordAnalytics(gameState, options.Layout)
+		recordReview(gameState)
+		if afterTest != nil {
+			afterTest(result)
+		}
 
 		// Show post-test menu
-		if !ui.ShowPostTestMenu(wordBank) {
-			break // Return to main menu
+		switch ui.ShowPostTestMenu(result) {
+		case ui.NextTest:
+			seed = 0
+		case ui.RetryTest:
+			// Keep the seed to generate the same text again
+		default:
+			return // Return to main menu
 		}
-		// Continue loop for another test
 	}
 }
 
-func showTestResults(gameState *game.GameState) {
+func showTestResults(gameState *game.GameState) game.TestResult {
 	result := gameState.GetTestResult()
 
 	fmt.Println("\n=== Test Results ===")
@@ -81,8 +556,19 @@ func showTestResults(gameState *game.GameState) {
 	} else {
 		fmt.Println("Results saved!")
 	}
+
+	return result
 }
 
-func showStats() {
-	ui.ShowStatsMenu()
+func showStats(options ui.TestOptions) {
+	layout, err := data.LoadLayout(options.Layout)
+	if err != nil {
+		// Without the layout the keys are assigned to fingers as on QWERTY
+		layout, err = data.LoadLayout(data.QWERTY)
+		if err != nil {
+			fmt.Printf("Error loading layout: %v\n", err)
+			return
+		}
+	}
+	ui.ShowStatsMenu(loadAnalytics(options.Layout).ByFinger(layout), layout)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"typr/data"
+	"typr/game"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -12,7 +13,11 @@ type MenuChoice int
 
 const (
 	StartTest MenuChoice = iota
+	StartCodeTest
+	ReviewTest
+	LessonTest
 	ViewStats
+	Settings
 	Exit
 )
 
@@ -52,13 +57,13 @@ func (m *Menu) Show() MenuChoice {
 	// Instructions
 	instructions := tview.NewTextView()
 	instructions.SetBorder(false)
-	instructions.SetText("[#6c7086]Navigate: [#cdd6f4]↑↓ or j/k[#6c7086] | Select: [#cdd6f4]Enter/Space/1-3[#6c7086] | Exit: [#cdd6f4]ESC/q, Ctrl+C")
+	instructions.SetText("[#6c7086]Navigate: [#cdd6f4]↑↓ or j/k[#6c7086] | Select: [#cdd6f4]Enter/Space/1-7[#6c7086] | Exit: [#cdd6f4]ESC/q, Ctrl+C")
 	instructions.SetTextAlign(tview.AlignCenter)
 	instructions.SetDynamicColors(true)
 
 	// Layout
 	flex.AddItem(asciiArt, 8, 0, false).
-		AddItem(m.menuView, 8, 0, false).
+		AddItem(m.menuView, 12, 0, false).
 		AddItem(instructions, 3, 0, false)
 
 	// Set up input handling
@@ -106,10 +111,26 @@ func (m *Menu) handleMenuInput(event *tcell.EventKey) *tcell.EventKey {
 			m.selected = true
 			m.app.Stop()
 		case '2':
-			m.choice = ViewStats
+			m.choice = StartCodeTest
 			m.selected = true
 			m.app.Stop()
 		case '3':
+			m.choice = ReviewTest
+			m.selected = true
+			m.app.Stop()
+		case '4':
+			m.choice = LessonTest
+			m.selected = true
+			m.app.Stop()
+		case '5':
+			m.choice = ViewStats
+			m.selected = true
+			m.app.Stop()
+		case '6':
+			m.choice = Settings
+			m.selected = true
+			m.app.Stop()
+		case '7':
 			m.choice = Exit
 			m.selected = true
 			m.app.Stop()
@@ -141,7 +162,11 @@ func (m *Menu) updateDisplay() {
 func (m *Menu) updateMenuDisplay(menuView *tview.TextView) {
 	options := []string{
 		"Start Typing Test (60 seconds)",
+		"Start Code Typing Test",
+		"Review Mistyped Words",
+		"Learn Touch Typing",
 		"View Statistics",
+		"Settings",
 		"Exit",
 	}
 
@@ -174,7 +199,15 @@ func ShowMainMenu() MenuChoice {
 	return menu.Show()
 }
 
-func ShowPostTestMenu(wordBank *data.WordBank) bool {
+type PostTestAction int
+
+const (
+	ReturnToMenu PostTestAction = iota
+	NextTest
+	RetryTest
+)
+
+func ShowPostTestMenu(result game.TestResult) PostTestAction {
 	app := tview.NewApplication()
 
 	// Create main container
@@ -184,22 +217,29 @@ func ShowPostTestMenu(wordBank *data.WordBank) bool {
 	message := tview.NewTextView()
 	message.SetBorder(true)
 	message.SetTitle(" Test Complete! ")
-	message.SetText("[#a6e3a1]Great job! Your results have been saved.\n\n[#cdd6f4]What would you like to do next?")
+	message.SetText(fmt.Sprintf(
+		"[#a6e3a1]Great job! Your results have been saved.\n\n"+
+			"[#f9e2af]WPM: [#cdd6f4]%.2f   [#f9e2af]Accuracy: [#cdd6f4]%.2f%%   [#f9e2af]Time: [#cdd6f4]%.1fs\n"+
+			"[#f9e2af]Words: [#cdd6f4]%d   [#f9e2af]Correct chars: [#cdd6f4]%d   [#f9e2af]Errors: [#cdd6f4]%d\n"+
+			"[#6c7086]Seed: %d\n\n"+
+			"[#cdd6f4]What would you like to do next?",
+		result.WPM, result.Accuracy, result.TestDuration.Seconds(),
+		result.TotalWords, result.TotalChars-result.Errors, result.Errors, result.Seed))
 	message.SetTextAlign(tview.AlignCenter)
 	message.SetDynamicColors(true)
 
 	// Options
 	options := tview.NewTextView()
 	options.SetBorder(false)
-	options.SetText("[#f9e2af]Press [#cdd6f4]SPACE[#f9e2af] for another test | Press [#cdd6f4]ESC[#f9e2af] to return to main menu")
+	options.SetText("[#f9e2af]Press [#cdd6f4]SPACE[#f9e2af] for another test | Press [#cdd6f4]r[#f9e2af] to retry the same text | Press [#cdd6f4]ESC[#f9e2af] to return to main menu")
 	options.SetTextAlign(tview.AlignCenter)
 	options.SetDynamicColors(true)
 
 	// Layout
-	flex.AddItem(message, 8, 0, false).
+	flex.AddItem(message, 11, 0, false).
 		AddItem(options, 5, 0, false)
 
-	restart := false
+	action := ReturnToMenu
 
 	// Input handling
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -210,18 +250,22 @@ func ShowPostTestMenu(wordBank *data.WordBank) bool {
 			os.Exit(0)
 			return nil
 		case tcell.KeyEscape:
-			restart = false
+			action = ReturnToMenu
 			app.Stop()
 			return nil
 		case tcell.KeyRune:
 			char := event.Rune()
 			switch char {
 			case ' ':
-				restart = true
+				action = NextTest
+				app.Stop()
+				return nil
+			case 'r', 'R':
+				action = RetryTest
 				app.Stop()
 				return nil
 			case 'q', 'Q':
-				restart = false
+				action = ReturnToMenu
 				app.Stop()
 				return nil
 			}
@@ -234,10 +278,12 @@ func ShowPostTestMenu(wordBank *data.WordBank) bool {
 		panic(err)
 	}
 
-	return restart
+	return action
 }
 
-func ShowStatsMenu() {
+// ShowStatsMenu shows the saved results and, if there are any analytics,
+// speed and errors by finger, hand and row on layout.
+func ShowStatsMenu(fingers *data.FingerStats, layout *data.Layout) {
 	app := tview.NewApplication()
 
 	// Load results
@@ -299,14 +345,29 @@ func ShowStatsMenu() {
 
 		for i := start; i < len(results); i++ {
 			r := results[i]
-			recentText += fmt.Sprintf("[#6c7086]%s: [#cdd6f4]%.2f WPM, %.2f%% accuracy\n",
+			recentText += fmt.Sprintf("[#6c7086]%s: [#cdd6f4]%.2f WPM, %.2f%% accuracy",
 				r.Timestamp.Format("Jan 2 15:04"), r.WPM, r.Accuracy)
+			if r.WordList != "" {
+				recentText += fmt.Sprintf(" [#6c7086](%s, seed %d)", r.WordList, r.Seed)
+			}
+			recentText += "\n"
 		}
 		recentView.SetText(recentText)
 	} else {
 		recentView.SetText("[#6c7086]No recent tests to display")
 	}
 
+	// Finger analytics next to the recent tests
+	fingerView := tview.NewTextView()
+	fingerView.SetBorder(true)
+	fingerView.SetTitle(" Fingers (" + layout.Name + ") ")
+	fingerView.SetDynamicColors(true)
+	fingerView.SetText(fingerStatsText(fingers))
+
+	body := tview.NewFlex().
+		AddItem(recentView, 0, 3, false).
+		AddItem(fingerView, 0, 2, false)
+
 	// Instructions
 	instructions := tview.NewTextView()
 	instructions.SetBorder(false)
@@ -316,7 +377,7 @@ func ShowStatsMenu() {
 
 	// Layout
 	flex.AddItem(header, 10, 0, false).
-		AddItem(recentView, 0, 1, false).
+		AddItem(body, 0, 1, false).
 		AddItem(instructions, 3, 0, false)
 
 	// Input handling
@@ -344,3 +405,145 @@ func ShowStatsMenu() {
 		panic(err)
 	}
 }
+
+// fingerStatsText lists speed and error rate by finger, hand and row, and
+// compares same-finger bigrams with all bigrams.
+func fingerStatsText(stats *data.FingerStats) string {
+	if len(stats.Fingers) == 0 {
+		return "[#6c7086]No keystrokes recorded yet"
+	}
+
+	line := func(label string, keyStats *data.KeyStats) string {
+		if keyStats == nil || keyStats.Count == 0 {
+			return fmt.Sprintf("[#6c7086]%-13s -\n", label)
+		}
+		return fmt.Sprintf("[#f9e2af]%-13s [#cdd6f4]%5.1f WPM  %5.1f%% errors\n", label, keyStats.WPM(), keyStats.ErrorRate()*100)
+	}
+
+	var text string
+	for finger := data.LeftPinky; finger <= data.RightPinky; finger++ {
+		if finger == data.LeftThumb || finger == data.RightThumb {
+			continue
+		}
+		text += line(finger.String(), stats.Fingers[finger])
+	}
+	text += "\n"
+	for _, hand := range []data.Hand{data.Left, data.Right} {
+		text += line(hand.String()+" hand", stats.Hands[hand])
+	}
+	text += "\n"
+	for row, name := range data.RowNames {
+		text += line(name+" row", stats.Rows[row])
+	}
+	text += "\n"
+	text += line("same finger", &stats.SameFinger)
+	text += line("all bigrams", &stats.Bigrams)
+	return text
+}
+
+// ShowConfirm asks a yes/no question and reports whether the user accepted.
+func ShowConfirm(title, message string) bool {
+	app := tview.NewApplication()
+
+	// Create main container
+	flex := tview.NewFlex().SetDirection(tview.FlexRow)
+
+	// Question
+	question := tview.NewTextView()
+	question.SetBorder(true)
+	question.SetTitle(title)
+	question.SetText("\n[#cdd6f4]" + tview.Escape(message))
+	question.SetTextAlign(tview.AlignCenter)
+	question.SetDynamicColors(true)
+
+	// Options
+	options := tview.NewTextView()
+	options.SetBorder(false)
+	options.SetText("[#f9e2af]Press [#cdd6f4]Enter/y[#f9e2af] to continue | Press [#cdd6f4]ESC/n[#f9e2af] to skip")
+	options.SetTextAlign(tview.AlignCenter)
+	options.SetDynamicColors(true)
+
+	// Layout
+	flex.AddItem(question, 5, 0, false).
+		AddItem(options, 3, 0, false)
+
+	accepted := false
+
+	// Input handling
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlC, tcell.KeyCtrlD:
+			// Force exit
+			app.Stop()
+			os.Exit(0)
+			return nil
+		case tcell.KeyEnter:
+			accepted = true
+			app.Stop()
+			return nil
+		case tcell.KeyEscape:
+			app.Stop()
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'y', 'Y':
+				accepted = true
+				app.Stop()
+			case 'n', 'N', 'q', 'Q':
+				app.Stop()
+			}
+			return nil
+		}
+		return event
+	})
+
+	// Run the prompt
+	if err := app.SetRoot(flex, true).Run(); err != nil {
+		panic(err)
+	}
+
+	return accepted
+}
+
+// ShowMessage shows a message until any key is pressed.
+func ShowMessage(title, message string) {
+	app := tview.NewApplication()
+
+	// Create main container
+	flex := tview.NewFlex().SetDirection(tview.FlexRow)
+
+	// Message
+	text := tview.NewTextView()
+	text.SetBorder(true)
+	text.SetTitle(title)
+	text.SetText("\n[#cdd6f4]" + tview.Escape(message))
+	text.SetTextAlign(tview.AlignCenter)
+	text.SetDynamicColors(true)
+
+	// Options
+	options := tview.NewTextView()
+	options.SetBorder(false)
+	options.SetText("[#f9e2af]Press any key to continue")
+	options.SetTextAlign(tview.AlignCenter)
+	options.SetDynamicColors(true)
+
+	// Layout
+	flex.AddItem(text, 6, 0, false).
+		AddItem(options, 3, 0, false)
+
+	// Input handling
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlC || event.Key() == tcell.KeyCtrlD {
+			// Force exit
+			app.Stop()
+			os.Exit(0)
+		}
+		app.Stop()
+		return nil
+	})
+
+	// Run the message
+	if err := app.SetRoot(flex, true).Run(); err != nil {
+		panic(err)
+	}
+}
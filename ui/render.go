@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"typr/funbox"
+	"typr/game"
+	"unicode/utf8"
+)
+
+// textRenderer lays out the game's words as lines of display cells. Every
+// renderer works from the same game state and cell conversion, so that
+// modifiers and blind mode apply regardless of the layout.
+type textRenderer interface {
+	Lines(g *game.GameState, lineWidth int) [][]funbox.Cell
+}
+
+// textLine is a range of word indices displayed on one line.
+type textLine struct {
+	start, end int
+}
+
+// overlayRenderer wraps the text over several lines and scrolls a line at a
+// time as the user progresses.
+type overlayRenderer struct {
+	firstWord int
+}
+
+func (r *overlayRenderer) Lines(g *game.GameState, lineWidth int) [][]funbox.Cell {
+	// Keep the current word on the second line (or further down for code)
+	maxLines, scrollLine := 4, 1
+	if g.CodeMode {
+		maxLines, scrollLine = 16, 5
+	}
+
+	lines := layoutLines(g, r.firstWord, maxLines, lineWidth)
+	if len(lines) > 0 && g.CurrentWordIdx >= lines[len(lines)-1].end {
+		r.firstWord = g.CurrentWordIdx
+		lines = layoutLines(g, r.firstWord, maxLines, lineWidth)
+	}
+	for idx, line := range lines {
+		if g.CurrentWordIdx >= line.start && g.CurrentWordIdx < line.end && idx > scrollLine {
+			r.firstWord = lines[idx-scrollLine].start
+			lines = layoutLines(g, r.firstWord, maxLines, lineWidth)
+			break
+		}
+	}
+
+	cells := make([][]funbox.Cell, len(lines))
+	for i, line := range lines {
+		cells[i] = lineCells(g, line)
+	}
+	return cells
+}
+
+// tapeRenderer shows the text on a single line that scrolls horizontally,
+// keeping the cursor fixed in the middle of the view.
+type tapeRenderer struct{}
+
+// tapeHistory is the number of finished words kept to the left of the cursor.
+const tapeHistory = 20
+
+func (r *tapeRenderer) Lines(g *game.GameState, lineWidth int) [][]funbox.Cell {
+	center := lineWidth / 2
+
+	// Collect enough words on both sides of the cursor to fill the line
+	line := textLine{start: max(g.CurrentWordIdx-tapeHistory, 0), end: g.CurrentWordIdx}
+	for length := 0; length <= center && g.HasWord(line.end); line.end++ {
+		length += utf8.RuneCountInString(g.WordAt(line.end)) + 1
+	}
+	if line.end == line.start {
+		return nil
+	}
+	cells := lineCells(g, line)
+
+	cursor := len(cells)
+	for i, cell := range cells {
+		if cell.State == funbox.CellCursor {
+			cursor = i
+			break
+		}
+	}
+
+	tape := make([]funbox.Cell, lineWidth)
+	for col := range tape {
+		idx := cursor - center + col
+		if idx >= 0 && idx < len(cells) {
+			tape[col] = cells[idx]
+		} else {
+			tape[col] = funbox.Cell{Char: ' ', Word: -1, State: funbox.CellHidden}
+		}
+	}
+	return [][]funbox.Cell{tape}
+}
+
+// layoutLines splits the words starting at from into at most maxLines lines
+// of lineWidth columns. Only the words that are displayed are pulled from
+// the game's word source.
+func layoutLines(g *game.GameState, from, maxLines, lineWidth int) []textLine {
+	lines := make([]textLine, 0, maxLines)
+	line := textLine{start: from, end: from}
+	lineLength := 0
+
+	for i := from; len(lines) < maxLines && g.HasWord(i); i++ {
+		width := max(utf8.RuneCountInString(g.WordAt(i)), utf8.RuneCountInString(g.InputAt(i)))
+
+		if g.CodeMode || (lineLength > 0 && lineLength+width >= lineWidth) {
+			if line.end > line.start {
+				lines = append(lines, line)
+			}
+			line = textLine{start: i, end: i}
+			lineLength = 0
+			if len(lines) == maxLines {
+				break
+			}
+		}
+
+		line.end = i + 1
+		lineLength += width
+		if !g.NoSpace {
+			lineLength++
+		}
+	}
+
+	if line.end > line.start && len(lines) < maxLines {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// lineCells converts the words of a line into display cells, including the
+// separators between them.
+func lineCells(g *game.GameState, line textLine) []funbox.Cell {
+	var cells []funbox.Cell
+
+	for i := line.start; i < line.end; i++ {
+		cells = appendWordCells(g, cells, i)
+
+		if g.NoSpace {
+			continue
+		}
+		typed, word := utf8.RuneCountInString(g.InputAt(i)), utf8.RuneCountInString(g.WordAt(i))
+		cursorOnSeparator := i == g.CurrentWordIdx && typed >= word && !g.Finished
+		if cursorOnSeparator {
+			cells = append(cells, funbox.Cell{Char: ' ', Word: i, State: funbox.CellCursor})
+		} else if i < line.end-1 {
+			cells = append(cells, funbox.Cell{Char: ' ', Word: i, State: funbox.CellUntyped})
+		}
+	}
+	return cells
+}
+
+func appendWordCells(g *game.GameState, cells []funbox.Cell, i int) []funbox.Cell {
+	word := []rune(g.WordAt(i))
+	typed := []rune(g.InputAt(i))
+
+	for j, char := range word {
+		cell := funbox.Cell{Char: char, Word: i}
+		switch {
+		case i == g.CurrentWordIdx && j == len(typed) && !g.Finished:
+			cell.State = funbox.CellCursor
+		case j < len(typed):
+			if typed[j] == char {
+				cell.State = funbox.CellCorrect
+			} else {
+				cell.State = funbox.CellIncorrect
+			}
+		case i < g.CurrentWordIdx:
+			// Skipped character of a finished word
+			cell.State = funbox.CellIncorrect
+		default:
+			cell.State = funbox.CellUntyped
+		}
+		cells = append(cells, cell)
+	}
+
+	// Extra characters typed beyond the word
+	if len(typed) > len(word) {
+		for _, char := range typed[len(word):] {
+			cells = append(cells, funbox.Cell{Char: char, Word: i, State: funbox.CellIncorrect})
+		}
+	}
+	return cells
+}
@@ -0,0 +1,285 @@
+package ui
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"maps"
+	"os"
+	"slices"
+	"typr/data"
+	"typr/funbox"
+)
+
+// Text sources for word tests
+const (
+	SourceWords  = "words"
+	SourceMarkov = "markov"
+)
+
+type TestOptions struct {
+	Countdown  bool
+	AutoIndent bool
+	Blind      bool
+	Tape       bool
+	Adaptive   bool
+	Review     bool
+	Generate   data.GenerateOptions
+	Filter     data.WordFilter
+	Layout     string
+	Modifiers  []string
+	WordList   string
+	Source     string
+	Corpus     string
+	CodeDir    string
+	CodeExts   []string
+
+	// Seed reproduces the text of an earlier test. Zero picks a new seed.
+	Seed int64
+}
+
+func DefaultTestOptions() TestOptions {
+	return TestOptions{
+		Countdown:  false,
+		AutoIndent: true,
+		WordList:   data.DefaultWordList,
+		Layout:     data.QWERTY,
+		Source:     SourceWords,
+	}
+}
+
+type setting struct {
+	label  string
+	value  func() string
+	change func()
+}
+
+func toggleSetting(label string, enabled *bool) setting {
+	return setting{
+		label: label,
+		value: func() string {
+			if *enabled {
+				return "on"
+			}
+			return "off"
+		},
+		change: func() { *enabled = !*enabled },
+	}
+}
+
+// listSetting toggles whether name is part of list.
+func listSetting(label, name string, list *[]string) setting {
+	return setting{
+		label: label,
+		value: func() string {
+			if slices.Contains(*list, name) {
+				return "on"
+			}
+			return "off"
+		},
+		change: func() {
+			if i := slices.Index(*list, name); i >= 0 {
+				*list = slices.Delete(*list, i, i+1)
+			} else {
+				*list = append(*list, name)
+			}
+		},
+	}
+}
+
+// choiceSetting cycles through a fixed set of values.
+func choiceSetting(label string, selected *string, choices []string) setting {
+	return setting{
+		label: label,
+		value: func() string { return *selected },
+		change: func() {
+			next := (slices.Index(choices, *selected) + 1) % len(choices)
+			*selected = choices[next]
+		},
+	}
+}
+
+// wordListSetting cycles through the available word lists.
+func wordListSetting(selected *string, lists []data.WordList) setting {
+	return setting{
+		label: "Word list",
+		value: func() string {
+			for _, list := range lists {
+				if list.ID == *selected {
+					return list.String()
+				}
+			}
+			return *selected
+		},
+		change: func() {
+			if len(lists) == 0 {
+				return
+			}
+			next := 0
+			for i, list := range lists {
+				if list.ID == *selected {
+					next = (i + 1) % len(lists)
+				}
+			}
+			*selected = lists[next].ID
+		},
+	}
+}
+
+// bandSetting cycles the frequency band of the word filter.
+func bandSetting(filter *data.WordFilter) setting {
+	bands := append([]string{""}, data.RankBands...)
+	current := func() int {
+		for i, band := range bands {
+			minRank, maxRank, _ := data.ParseRankBand(band)
+			if filter.MinRank == minRank && filter.MaxRank == maxRank {
+				return i
+			}
+		}
+		return -1
+	}
+
+	return setting{
+		label: "Frequency band",
+		value: func() string {
+			switch i := current(); i {
+			case -1:
+				return fmt.Sprintf("ranks %d-%d", filter.MinRank, filter.MaxRank)
+			case 0:
+				return "all"
+			default:
+				return bands[i]
+			}
+		},
+		change: func() {
+			next := (current() + 1) % len(bands)
+			filter.MinRank, filter.MaxRank, _ = data.ParseRankBand(bands[next])
+		},
+	}
+}
+
+// charSetSetting cycles the allowed characters of the word filter through
+// the named character sets.
+func charSetSetting(filter *data.WordFilter) setting {
+	names := append([]string{""}, slices.Sorted(maps.Keys(data.CharSets))...)
+	current := func() int {
+		return slices.IndexFunc(names, func(name string) bool {
+			return data.ResolveCharSet(name) == filter.Allowed
+		})
+	}
+
+	return setting{
+		label: "Allowed characters",
+		value: func() string {
+			switch i := current(); i {
+			case -1:
+				return filter.Allowed
+			case 0:
+				return "all"
+			default:
+				return names[i]
+			}
+		},
+		change: func() {
+			next := (current() + 1) % len(names)
+			filter.Allowed = data.ResolveCharSet(names[next])
+		},
+	}
+}
+
+func ShowSettingsMenu(options *TestOptions, wordLists []data.WordList) {
+	app := tview.NewApplication()
+
+	settings := []setting{
+		wordListSetting(&options.WordList, wordLists),
+		bandSetting(&options.Filter),
+		charSetSetting(&options.Filter),
+		choiceSetting("Keyboard layout to emulate", &options.Layout, data.LayoutIDs()),
+		choiceSetting("Text source (markov needs -corpus)", &options.Source, []string{SourceWords, SourceMarkov}),
+		toggleSetting("3-2-1 countdown before the test", &options.Countdown),
+		toggleSetting("Auto-skip indentation in code tests", &options.AutoIndent),
+		toggleSetting("Blind mode (hide errors until the end)", &options.Blind),
+		toggleSetting("Tape mode (single scrolling line)", &options.Tape),
+		toggleSetting("Adaptive (focus on weak keys)", &options.Adaptive),
+		toggleSetting("Mix due review words into tests", &options.Review),
+		toggleSetting("Punctuation", &options.Generate.Punctuation),
+		toggleSetting("Numbers", &options.Generate.Numbers),
+	}
+	for _, name := range funbox.Names() {
+		settings = append(settings, listSetting("Funbox: "+name, name, &options.Modifiers))
+	}
+	selected := 0
+
+	// Create main container
+	flex := tview.NewFlex().SetDirection(tview.FlexRow)
+
+	settingsView := tview.NewTextView()
+	settingsView.SetBorder(true)
+	settingsView.SetTitle(" Settings ")
+	settingsView.SetDynamicColors(true)
+	settingsView.SetBorderPadding(1, 1, 2, 2)
+
+	// Instructions
+	instructions := tview.NewTextView()
+	instructions.SetBorder(false)
+	instructions.SetText("[#6c7086]Navigate: [#cdd6f4]↑↓ or j/k[#6c7086] | Change: [#cdd6f4]Enter/Space[#6c7086] | Back: [#cdd6f4]ESC/q")
+	instructions.SetTextAlign(tview.AlignCenter)
+	instructions.SetDynamicColors(true)
+
+	// Layout
+	flex.AddItem(settingsView, 0, 1, false).
+		AddItem(instructions, 3, 0, false)
+
+	render := func() {
+		var text string
+		for i, s := range settings {
+			if i == selected {
+				text += fmt.Sprintf("[#181825:#f9e2af] > %-40s %s [#cdd6f4:-]\n", s.label, s.value())
+			} else {
+				text += fmt.Sprintf("[#6c7086]   %-40s [#cdd6f4]%s[-]\n", s.label, s.value())
+			}
+		}
+		settingsView.SetText(text)
+	}
+
+	// Input handling
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlC, tcell.KeyCtrlD:
+			// Force exit
+			app.Stop()
+			os.Exit(0)
+			return nil
+		case tcell.KeyEscape:
+			app.Stop()
+			return nil
+		case tcell.KeyUp:
+			selected = max(selected-1, 0)
+		case tcell.KeyDown:
+			selected = min(selected+1, len(settings)-1)
+		case tcell.KeyEnter:
+			settings[selected].change()
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'k':
+				selected = max(selected-1, 0)
+			case 'j':
+				selected = min(selected+1, len(settings)-1)
+			case ' ':
+				settings[selected].change()
+			case 'q', 'Q':
+				app.Stop()
+				return nil
+			}
+		}
+		render()
+		return nil
+	})
+
+	render()
+
+	// Run the settings menu
+	if err := app.SetRoot(flex, true).Run(); err != nil {
+		panic(err)
+	}
+}
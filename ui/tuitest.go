@@ -7,7 +7,10 @@ import (
 	"os"
 	"strings"
 	"time"
+	"typr/data"
+	"typr/funbox"
 	"typr/game"
+	"unicode"
 )
 
 type TUITest struct {
@@ -15,22 +18,35 @@ type TUITest struct {
 	gameState *game.GameState
 	textView  *tview.TextView
 	statsView *tview.TextView
-	typedText string
-	allText   string
+	options   TestOptions
+	countdown int
+	renderer  textRenderer
+	modifiers []funbox.Modifier
+
+	// layout and remapper emulate a keyboard layout other than the system's
+	layout       *data.Layout
+	remapper     *data.Remapper
+	keyboardView *tview.TextView
 }
 
-func NewTUITest() *TUITest {
+func NewTUITest(options TestOptions) *TUITest {
 	return &TUITest{
-		app:       tview.NewApplication(),
-		typedText: "",
+		app:     tview.NewApplication(),
+		options: options,
 	}
 }
 
 func (t *TUITest) RunTypingTest(gameState *game.GameState) {
 	t.gameState = gameState
 
-	// Join all words into one text string
-	t.allText = strings.Join(gameState.Words, " ")
+	// The game only records modifiers that were created successfully
+	t.modifiers, _ = funbox.NewAll(gameState.Modifiers, gameState.Seed)
+
+	if t.options.Tape && !gameState.CodeMode {
+		t.renderer = &tapeRenderer{}
+	} else {
+		t.renderer = &overlayRenderer{}
+	}
 
 	// Create the main flex container
 	flex := tview.NewFlex().SetDirection(tview.FlexRow)
@@ -50,7 +66,11 @@ func (t *TUITest) RunTypingTest(gameState *game.GameState) {
 	// Instructions at bottom
 	instructions := tview.NewTextView()
 	instructions.SetBorder(true).SetTitle(" Instructions ")
-	instructions.SetText("Type the text above. No backspace corrections allowed. Press ESC to exit.")
+	if gameState.CodeMode {
+		instructions.SetText("Type the code above. Enter starts a new line, Tab indents. Press ESC to exit.")
+	} else {
+		instructions.SetText("Type the text above. No backspace corrections allowed. Press ESC to exit.")
+	}
 	instructions.SetTextAlign(tview.AlignCenter)
 	instructions.SetBorderPadding(0, 0, 1, 1)
 
@@ -59,11 +79,25 @@ func (t *TUITest) RunTypingTest(gameState *game.GameState) {
 		AddItem(t.textView, 0, 1, false).
 		AddItem(instructions, 5, 0, false)
 
+	// Keyboard of the emulated layout below everything else
+	if t.loadLayout() {
+		t.keyboardView = tview.NewTextView()
+		t.keyboardView.SetBorder(true).SetTitle(" " + t.layout.Name + " ")
+		t.keyboardView.SetDynamicColors(true)
+		t.keyboardView.SetTextAlign(tview.AlignCenter)
+		flex.AddItem(t.keyboardView, len(t.layout.Rows)+2, 0, false)
+	}
+
 	// Set up input capture
 	t.app.SetInputCapture(t.handleInput)
 
-	// Start the game
-	gameState.Start()
+	// The timer starts on the first keystroke, or after the countdown
+	if t.options.Countdown {
+		t.countdown = 3
+		go t.countdownLoop()
+	} else {
+		gameState.WaitForInput()
+	}
 
 	// Start timer for updates
 	go t.updateLoop()
@@ -93,6 +127,18 @@ func (t *TUITest) handleInput(event *tcell.EventKey) *tcell.EventKey {
 		// Ignore backspace events - no corrections allowed
 		return nil
 
+	case tcell.KeyEnter, tcell.KeyTab:
+		if !t.gameState.CodeMode || t.gameState.Finished {
+			return nil
+		}
+		if event.Key() == tcell.KeyEnter {
+			t.gameState.ProcessChar('\n')
+		} else {
+			t.gameState.ProcessChar('\t')
+		}
+		t.updateDisplay()
+		return nil
+
 	case tcell.KeyRune:
 		char := event.Rune()
 
@@ -106,8 +152,10 @@ func (t *TUITest) handleInput(event *tcell.EventKey) *tcell.EventKey {
 			return nil // Ignore other input after test completion
 		}
 
-		if char >= 32 && char <= 126 { // Printable characters
-			t.typedText += string(char)
+		if unicode.IsPrint(char) {
+			if t.remapper != nil {
+				char = t.remapper.Remap(char)
+			}
 			t.gameState.ProcessChar(char)
 			t.updateDisplay()
 		}
@@ -117,6 +165,22 @@ func (t *TUITest) handleInput(event *tcell.EventKey) *tcell.EventKey {
 	return event
 }
 
+func (t *TUITest) countdownLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range t.countdown {
+		<-ticker.C
+		t.app.QueueUpdateDraw(func() {
+			t.countdown--
+			if t.countdown == 0 && !t.gameState.Finished {
+				t.gameState.Start()
+			}
+			t.updateDisplay()
+		})
+	}
+}
+
 func (t *TUITest) updateLoop() {
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
@@ -144,69 +208,166 @@ func (t *TUITest) updateDisplay() {
 	if timeLeft < 0 {
 		timeLeft = 0
 	}
+	if t.gameState.TestDuration <= 0 {
+		// Untimed tests show the time spent instead
+		timeLeft = t.gameState.GetElapsedTime()
+	}
 
-	statsText := fmt.Sprintf(
-		"[#f9e2af]Time: [#cdd6f4]%.1fs   [#f9e2af]WPM: [#cdd6f4]%.1f   [#f9e2af]Accuracy: [#cdd6f4]%.1f%%   [#f9e2af]Errors: [#cdd6f4]%d   [#f9e2af]Progress: [#cdd6f4]%.1f%%",
-		timeLeft.Seconds(),
-		t.gameState.CalculateWPM(),
-		t.gameState.CalculateAccuracy(),
-		t.gameState.Errors,
-		t.gameState.GetProgress(),
-	)
+	var statsText string
+	if t.hideErrors() {
+		statsText = fmt.Sprintf(
+			"[#f9e2af]Time: [#cdd6f4]%.1fs   [#f9e2af]WPM: [#cdd6f4]%.1f   [#f9e2af]Progress: [#cdd6f4]%.1f%%   [#6c7086](blind mode)",
+			timeLeft.Seconds(),
+			t.gameState.CalculateWPM(),
+			t.gameState.GetProgress(),
+		)
+	} else {
+		statsText = fmt.Sprintf(
+			"[#f9e2af]Time: [#cdd6f4]%.1fs   [#f9e2af]WPM: [#cdd6f4]%.1f   [#f9e2af]Accuracy: [#cdd6f4]%.1f%%   [#f9e2af]Errors: [#cdd6f4]%d   [#f9e2af]Progress: [#cdd6f4]%.1f%%",
+			timeLeft.Seconds(),
+			t.gameState.CalculateWPM(),
+			t.gameState.CalculateAccuracy(),
+			t.gameState.Errors,
+			t.gameState.GetProgress(),
+		)
+	}
 
 	if t.gameState.Finished {
 		statsText += "\n[#f38ba8]TEST COMPLETED! Press ESC/q to exit, Ctrl+C to force quit."
 	}
 
+	switch {
+	case t.countdown > 0:
+		statsText += fmt.Sprintf("\n[#f9e2af]Starting in %d...", t.countdown)
+	case t.gameState.Status == game.StatusWaiting:
+		statsText += "\n[#6c7086]The timer starts with your first keystroke."
+	}
+
 	t.statsView.SetText(statsText)
 
 	// Update text with overlay
 	t.updateTextOverlay()
+	t.updateKeyboard()
 }
 
-func (t *TUITest) updateTextOverlay() {
-	var result strings.Builder
-	lineWidth := 90
-	currentLineLength := 0
-
-	for i, char := range t.allText {
-		// Add line breaks at word boundaries when approaching width limit
-		if currentLineLength >= lineWidth && char == ' ' {
-			result.WriteString("\n")
-			currentLineLength = 0
-			continue
-		}
+// loadLayout sets up the emulation of the selected layout and reports
+// whether there is one. Only layouts that load can be selected, but one that
+// broke since then is simply not emulated.
+func (t *TUITest) loadLayout() bool {
+	if t.options.Layout == "" || t.options.Layout == data.QWERTY {
+		return false
+	}
+
+	system, err := data.LoadLayout(data.QWERTY)
+	if err != nil {
+		return false
+	}
+	layout, err := data.LoadLayout(t.options.Layout)
+	if err != nil {
+		return false
+	}
+
+	t.layout = layout
+	t.remapper = data.NewRemapper(system, layout)
+	return true
+}
+
+// updateKeyboard shows the emulated layout with the key to type next
+// highlighted.
+func (t *TUITest) updateKeyboard() {
+	if t.keyboardView == nil {
+		return
+	}
+
+	next := ' '
+	word := []rune(t.gameState.GetCurrentWord())
+	if t.gameState.CurrentCharIdx < len(word) {
+		next = word[t.gameState.CurrentCharIdx]
+	}
 
-		if i < len(t.typedText) {
-			typedChar := rune(t.typedText[i])
-			if typedChar == char {
-				// Correct character - Catppuccin green text
-				result.WriteString(fmt.Sprintf("[#a6e3a1]%c[-]", char))
+	var result strings.Builder
+	for _, row := range t.layout.Rows {
+		for _, key := range row {
+			if !t.gameState.Finished && (key.Base == next || key.Shift == next) {
+				result.WriteString(cursorCell(key.Base))
 			} else {
-				// Incorrect character - Catppuccin red text
-				result.WriteString(fmt.Sprintf("[#f38ba8]%c[-]", char))
+				result.WriteString(colorCell("#6c7086", key.Base))
 			}
-		} else if i == len(t.typedText) {
-			// Current cursor position - block character background
-			result.WriteString(fmt.Sprintf("[#181825:#cdd6f4]%c[#cdd6f4:-]", char))
-		} else {
-			// Untyped text - Catppuccin muted
-			result.WriteString(fmt.Sprintf("[#6c7086]%c[-]", char))
+			result.WriteString(" ")
 		}
+		result.WriteString("\n")
+	}
 
-		currentLineLength++
-		if char == '\n' {
-			currentLineLength = 0
-		}
+	t.keyboardView.SetText(result.String())
+}
+
+func (t *TUITest) updateTextOverlay() {
+	var result strings.Builder
+	g := t.gameState
+
+	lineWidth := 90
+	if _, _, width, _ := t.textView.GetInnerRect(); width > 0 && width < lineWidth {
+		lineWidth = width
 	}
 
-	// Handle extra characters typed beyond the text
-	if len(t.typedText) > len(t.allText) {
-		extraText := t.typedText[len(t.allText):]
-		for _, char := range extraText {
-			result.WriteString(fmt.Sprintf("[#f38ba8]%c[-]", char))
+	frame := funbox.Frame{CurrentWord: g.CurrentWordIdx, Elapsed: g.GetElapsedTime()}
+	for _, cells := range t.renderer.Lines(g, lineWidth) {
+		cells = funbox.ModifyLine(cells, frame, t.modifiers)
+		if t.hideErrors() {
+			cells = neutralCells(cells)
+		}
+		for _, cell := range cells {
+			result.WriteString(renderCell(cell))
 		}
+		result.WriteString("\n")
 	}
 
 	t.textView.SetText(result.String())
 }
+
+// hideErrors reports whether mistakes should stay hidden, which in blind mode
+// is the case until the test is over.
+func (t *TUITest) hideErrors() bool {
+	return t.options.Blind && !t.gameState.Finished
+}
+
+// neutralCells shows typed text without revealing whether it was correct.
+// Characters skipped in finished words are shown as typed as well.
+func neutralCells(cells []funbox.Cell) []funbox.Cell {
+	for i := range cells {
+		switch cells[i].State {
+		case funbox.CellCorrect, funbox.CellIncorrect:
+			cells[i].State = funbox.CellTyped
+		}
+	}
+	return cells
+}
+
+func renderCell(cell funbox.Cell) string {
+	switch cell.State {
+	case funbox.CellCorrect:
+		// Correct character - Catppuccin green text
+		return colorCell("#a6e3a1", cell.Char)
+	case funbox.CellIncorrect:
+		// Incorrect character - Catppuccin red text
+		return colorCell("#f38ba8", cell.Char)
+	case funbox.CellCursor:
+		// Current cursor position - block character background
+		return cursorCell(cell.Char)
+	case funbox.CellTyped:
+		return colorCell("#cdd6f4", cell.Char)
+	case funbox.CellHidden:
+		return " "
+	default:
+		// Untyped text - Catppuccin muted
+		return colorCell("#6c7086", cell.Char)
+	}
+}
+
+func colorCell(color string, char rune) string {
+	return fmt.Sprintf("[%s]%c[-]", color, char)
+}
+
+func cursorCell(char rune) string {
+	return fmt.Sprintf("[#181825:#cdd6f4]%c[#cdd6f4:-]", char)
+}